@@ -0,0 +1,159 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_final_project/service"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AuditHandler — GET /api/audit возвращает журнал изменений задач, которые видит пользователь,
+// от новых записей к старым. Фильтры: task, list, user (кто выполнил действие), action,
+// from и to (даты в формате 20060102 по UTC), limit; before — next_before из предыдущего ответа.
+func (h *Handlers) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+	filter := service.AuditFilter{UserID: currentUser(r), Limit: service.TaskQueryLimit}
+	if err := parseAuditFilter(r.URL.Query(), &filter); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.requireListRole(w, r, filter.ListID, service.RoleViewer) {
+		return
+	}
+
+	page, err := h.TaskStore.GetAuditLog(filter)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// TaskAuditHandler — GET /api/task/audit?id= возвращает журнал изменений одной задачи,
+// в том числе удалённой в корзину или окончательно. Принимает те же фильтры, что и /api/audit.
+func (h *Handlers) TaskAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+	query := r.URL.Query()
+	idStr := query.Get("id")
+	if idStr == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор задачи")
+		return
+	}
+	query.Del("task")
+	query.Del("list")
+
+	filter := service.AuditFilter{UserID: currentUser(r), TaskID: int64(id), Limit: service.TaskQueryLimit}
+	if err := parseAuditFilter(query, &filter); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := h.TaskStore.GetAuditLog(filter)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	// задача, созданная до появления журнала, может в нём не встречаться
+	if len(page.Entries) == 0 && filter.BeforeID == 0 {
+		if found, err := h.taskExists(currentUser(r), id); err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+			return
+		} else if !found {
+			writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// taskExists сообщает, видит ли пользователь задачу — в том числе выполненную или удалённую в корзину
+func (h *Handlers) taskExists(userID int64, id int) (bool, error) {
+	_, err := h.TaskStore.GetTaskWithArchived(userID, id)
+	if err == sql.ErrNoRows {
+		_, err = h.TaskStore.GetTrashedTask(userID, id)
+	}
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// parseAuditFilter разбирает фильтры журнала аудита: task, list, user, action, from, to, before и limit
+func parseAuditFilter(query url.Values, filter *service.AuditFilter) error {
+	for _, param := range []struct {
+		name    string
+		value   *int64
+		message string
+	}{
+		{"task", &filter.TaskID, "Некорректный идентификатор задачи"},
+		{"list", &filter.ListID, "Некорректный идентификатор списка"},
+		{"before", &filter.BeforeID, "Некорректное значение параметра before"},
+	} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			return errors.New(param.message)
+		}
+		*param.value = id
+	}
+	if value := query.Get("user"); value != "" {
+		actorID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || actorID < 0 {
+			return errors.New("Некорректный идентификатор пользователя")
+		}
+		filter.ActorID = &actorID
+	}
+	if action := query.Get("action"); action != "" {
+		if !service.ValidAuditAction(action) {
+			return fmt.Errorf("Неизвестное действие: %s", action)
+		}
+		filter.Action = action
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *string
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(service.DateFormat, value); err != nil {
+			return fmt.Errorf("Некорректная дата в параметре %s: %s", param.name, value)
+		}
+		*param.value = value
+	}
+	if filter.From != "" && filter.To != "" && filter.From > filter.To {
+		return errors.New("Дата from не может быть позже даты to")
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return errors.New("Некорректный размер страницы")
+		}
+		filter.Limit = min(limit, service.TaskPageMaxLimit)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"go_final_project/model"
+	"go_final_project/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenCookie — cookie, в которой страница входа сохраняет токен
+const TokenCookie = "token"
+
+type signinRequest struct {
+	Password string `json:"password"`
+}
+
+// SigninHandler проверяет пароль и возвращает токен: {"token": "..."}
+func (h *Handlers) SigninHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+	if !h.Auth.Enabled() {
+		writeErrorResponse(w, http.StatusBadRequest, "Пароль не задан, вход по паролю не требуется")
+		return
+	}
+
+	var req signinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+		return
+	}
+
+	token, err := h.Auth.SignIn(req.Password, time.Now())
+	if err == service.ErrWrongPassword {
+		writeErrorResponse(w, http.StatusUnauthorized, "Неверный пароль")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка создания токена: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+	})
+}
+
+// RegisterHandler создаёт пользователя: {"login": "...", "password": "..."} -> {"id": "..."}
+func (h *Handlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+
+	var credentials model.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+		return
+	}
+	if err := service.ValidateCredentials(&credentials); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	hash, err := service.HashPassword(credentials.Password)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка хеширования пароля: "+err.Error())
+		return
+	}
+	id, err := h.UserStore.CreateUser(credentials.Login, hash)
+	if err == service.ErrLoginTaken {
+		writeErrorResponse(w, http.StatusConflict, "Логин уже занят")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model.TaskResponse{ID: strconv.FormatInt(id, 10)})
+}
+
+// LoginHandler проверяет логин и пароль пользователя и возвращает токен: {"token": "..."}
+func (h *Handlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+
+	var credentials model.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+		return
+	}
+
+	var found *model.User
+	user, err := h.UserStore.GetUserByLogin(strings.ToLower(strings.TrimSpace(credentials.Login)))
+	if err == nil {
+		found = &user
+	} else if err != sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	if !service.CheckPassword(found, credentials.Password) {
+		writeErrorResponse(w, http.StatusUnauthorized, "Неверный логин или пароль")
+		return
+	}
+
+	token, err := h.Auth.UserToken(user, time.Now())
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка создания токена: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+	})
+}
+
+type contextKey int
+
+const identityKey contextKey = iota
+
+// identity — кто выполняет запрос: пользователь (0 — общий список задач), если запрос
+// пришёл с личным токеном, его область действия, и идентификатор запроса для журнала аудита
+type identity struct {
+	UserID    int64
+	APIToken  bool
+	Scope     string
+	RequestID string
+}
+
+func currentIdentity(r *http.Request) identity {
+	id, _ := r.Context().Value(identityKey).(identity)
+	return id
+}
+
+// currentUser возвращает пользователя запроса, определённого в RequireAuth; 0 — общий список задач
+func currentUser(r *http.Request) int64 {
+	return currentIdentity(r).UserID
+}
+
+// currentActor возвращает пользователя и идентификатор запроса для методов хранилища,
+// которые записывают изменения в журнал аудита
+func currentActor(r *http.Request) service.Actor {
+	id := currentIdentity(r)
+	return service.Actor{UserID: id.UserID, RequestID: id.RequestID}
+}
+
+// requestID берёт идентификатор запроса из заголовка X-Request-ID, если он там есть и
+// состоит из латинских букв, цифр и знаков -_.:, иначе придумывает новый
+func requestID(r *http.Request) string {
+	value := r.Header.Get(service.RequestIDHeader)
+	valid := value != "" && len(value) <= service.MaxRequestIDLength
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		return value
+	}
+	value, err := service.NewRequestID()
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// requireWriteAccess отвечает 403, если запрос пришёл с токеном только для чтения.
+// Его вызывают обработчики, которые меняют задачи.
+func requireWriteAccess(w http.ResponseWriter, r *http.Request) bool {
+	if id := currentIdentity(r); id.APIToken && id.Scope != service.ScopeWrite {
+		writeErrorResponse(w, http.StatusForbidden, "Токен выдан только для чтения")
+		return false
+	}
+	return true
+}
+
+// RequireAuth определяет пользователя по личному токену из заголовка Authorization: Bearer
+// или по токену из cookie token и передаёт его обработчику next. Без токена запрос работает
+// с общим списком задач, если TODO_PASSWORD не задан, иначе отклоняется. Идентификатор
+// запроса возвращается в заголовке X-Request-ID.
+func (h *Handlers) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := requestID(r)
+		if requestID != "" {
+			w.Header().Set(service.RequestIDHeader, requestID)
+		}
+		var id identity
+		cookie, cookieErr := r.Cookie(TokenCookie)
+		switch header := r.Header.Get("Authorization"); {
+		case header != "":
+			bearer, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				writeErrorResponse(w, http.StatusUnauthorized, "Требуется аутентификация")
+				return
+			}
+			token, err := h.UserStore.GetAPITokenByHash(service.HashAPIToken(strings.TrimSpace(bearer)), time.Now())
+			if err == sql.ErrNoRows {
+				writeErrorResponse(w, http.StatusUnauthorized, "Требуется аутентификация")
+				return
+			} else if err != nil {
+				writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+				return
+			}
+			id = identity{UserID: token.UserID, APIToken: true, Scope: token.Scope}
+		case cookieErr == nil && cookie.Value != "":
+			userID, err := h.Auth.Verify(cookie.Value, h.UserStore.GetUserByID)
+			if err != nil {
+				writeErrorResponse(w, http.StatusUnauthorized, "Требуется аутентификация")
+				return
+			}
+			id.UserID = userID
+		case h.Auth.Enabled():
+			writeErrorResponse(w, http.StatusUnauthorized, "Требуется аутентификация")
+			return
+		}
+		id.RequestID = requestID
+		next(w, r.WithContext(context.WithValue(r.Context(), identityKey, id)))
+	}
+}
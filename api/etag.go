@@ -0,0 +1,69 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go_final_project/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// taskETag — ETag задачи: её версия в кавычках
+func taskETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// checkTaskVersion проверяет, что клиент меняет ту версию задачи, которую видел. Версию он
+// передаёт в If-Match (ETag из GET /api/task, * — любая версия) или, если заголовка нет,
+// в version. Без версии отвечает 428, при несовпадении — 412 с текущим состоянием задачи.
+func checkTaskVersion(w http.ResponseWriter, r *http.Request, task model.Tasks, version int64) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		for _, tag := range strings.Split(ifMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == taskETag(task.Version) {
+				return true
+			}
+		}
+	} else if version == 0 {
+		writeErrorResponse(w, http.StatusPreconditionRequired,
+			"Не указана версия задачи: передайте ETag из GET /api/task в заголовке If-Match или поле version")
+		return false
+	} else if version == task.Version {
+		return true
+	}
+	writeVersionConflict(w, task)
+	return false
+}
+
+// writeVersionConflict отвечает 412 и возвращает задачу в том виде, в каком она сейчас хранится
+func writeVersionConflict(w http.ResponseWriter, task model.Tasks) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", taskETag(task.Version))
+	w.WriteHeader(http.StatusPreconditionFailed)
+	response := model.TaskResponse{Error: "Задача изменена после того, как вы её открыли", Task: &task}
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeTaskChanged отвечает на изменение, которое не сохранилось, потому что задачу успели
+// изменить между проверкой версии и записью: 412 с текущим состоянием или 404, если задачи больше нет
+func (h *Handlers) writeTaskChanged(w http.ResponseWriter, r *http.Request, id int) {
+	task, err := h.TaskStore.GetTaskByID(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	writeVersionConflict(w, task)
+}
+
+// parseVersion разбирает версию задачи из параметра запроса; пустая строка — версия не указана
+func parseVersion(value string) (int64, bool) {
+	if value == "" {
+		return 0, true
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	return version, err == nil && version > 0
+}
@@ -3,24 +3,44 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go_final_project/model"
 	"go_final_project/service"
 	"log"
 	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Handlers работает с хранилищами через интерфейсы service.TaskStore, service.UserStore
+// и service.ListStore, поэтому их можно заменить, например, хранилищем в памяти в тестах
 type Handlers struct {
-	TaskService    *service.TaskService
-	TaskRepository *service.TaskRepository
+	TaskService *service.TaskService
+	TaskStore   service.TaskStore
+	UserStore   service.UserStore
+	ListStore   service.ListStore
+	// UndoWindow — сколько действует токен отмены, выданный при выполнении или удалении задачи
+	UndoWindow time.Duration
+	Auth       *service.Authenticator
 }
 
-func NewHandlers(db *sql.DB) *Handlers {
+// NewHandlers создаёт обработчики, которые хранят данные в базе db — SQLite или PostgreSQL
+func NewHandlers(db *service.DB, auth *service.Authenticator) *Handlers {
+	return NewHandlersWithStores(service.NewTaskRepository(db), service.NewUserRepository(db), service.NewListRepository(db), auth)
+}
+
+func NewHandlersWithStores(tasks service.TaskStore, users service.UserStore, lists service.ListStore, auth *service.Authenticator) *Handlers {
 	return &Handlers{
-		TaskService:    service.NewTaskService(),
-		TaskRepository: service.NewTaskRepository(db),
+		TaskService: service.NewTaskService(),
+		TaskStore:   tasks,
+		UserStore:   users,
+		ListStore:   lists,
+		UndoWindow:  service.DefaultUndoWindow,
+		Auth:        auth,
 	}
 }
 
@@ -31,7 +51,23 @@ func writeErrorResponse(w http.ResponseWriter, statusCode int, errMsg string) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// writeValidationError отвечает 400 и, если ошибка относится к конкретному полю задачи, указывает его
+func writeValidationError(w http.ResponseWriter, err error) {
+	var validationErr *service.ValidationError
+	if !errors.As(err, &validationErr) {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	response := model.TaskResponse{Error: validationErr.Message, Field: validationErr.Field}
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *Handlers) PostTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireWriteAccess(w, r) {
+		return
+	}
 	var task model.Tasks
 	err := json.NewDecoder(r.Body).Decode(&task)
 	if err != nil {
@@ -39,19 +75,15 @@ func (h *Handlers) PostTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if task.Title == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Не указан заголовок задачи")
+	if err := service.ValidateTask(time.Now(), &task); err != nil {
+		writeValidationError(w, err)
 		return
 	}
-
-	now := time.Now()
-	task.Date, err = service.ValidateTaskDate(now, task.Date, task.Repeat)
-	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+	if !h.requireListRole(w, r, task.ListID, service.RoleEditor) {
 		return
 	}
 
-	taskID, err := h.TaskRepository.CreateTask(task)
+	taskID, err := h.TaskStore.CreateTask(currentActor(r), task)
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
 		return
@@ -75,7 +107,7 @@ func (h *Handlers) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.TaskRepository.GetTaskByID(id)
+	task, err := h.TaskStore.GetTaskByID(currentUser(r), id)
 	if err == sql.ErrNoRows {
 		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
 		return
@@ -85,6 +117,7 @@ func (h *Handlers) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", taskETag(task.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(task)
 }
@@ -117,24 +150,170 @@ func (h *Handlers) GetNextDateHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(nextDate))
 }
 
-func (h *Handlers) GetTasksHandler(w http.ResponseWriter, r *http.Request) {
-	tasks, err := h.TaskRepository.GetAllTasks(service.TaskQueryLimit)
+func (h *Handlers) GetNextDatePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	dateStr := r.URL.Query().Get("date")
+	repeat := r.URL.Query().Get("repeat")
+	if dateStr == "" || repeat == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Отсутствует требуемый параметр")
+		return
+	}
+
+	now := time.Now()
+	if nowStr := r.URL.Query().Get("now"); nowStr != "" {
+		var err error
+		now, err = time.Parse(service.DateFormat, nowStr)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Неверный формат 'now'")
+			return
+		}
+	}
+
+	count := service.PreviewDefaultCount
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		var err error
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 {
+			writeErrorResponse(w, http.StatusBadRequest, "Некорректное значение 'count'")
+			return
+		}
+		if count > service.PreviewMaxCount {
+			count = service.PreviewMaxCount
+		}
+	}
+
+	var until time.Time
+	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
+		var err error
+		until, err = time.Parse(service.DateFormat, untilStr)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Неверный формат 'until'")
+			return
+		}
+	}
+
+	dates, err := h.TaskService.NextDates(now, dateStr, repeat, count, until)
 	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dates": dates,
+	})
+}
+
+func (h *Handlers) DescribeRepeatHandler(w http.ResponseWriter, r *http.Request) {
+	repeat := r.URL.Query().Get("repeat")
+	if repeat == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указано правило повторения")
+		return
+	}
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = service.LangRU
+	}
+
+	rule, description, err := h.TaskService.DescribeRepeat(repeat, lang)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rule":        rule,
+		"description": description,
+	})
+}
+
+// GetTasksHandler возвращает страницу задач. Параметры: list — id общего списка (без него выводятся
+// личные задачи), search — строка поиска, from и to — границы дат (20060102) включительно,
+// overdue=true — только просроченные задачи, repeating=true или false — только повторяющиеся
+// или только разовые, sort — date, title, id или created, order — asc или desc,
+// limit — размер страницы, cursor — next_cursor или prev_cursor из предыдущего ответа.
+func (h *Handlers) GetTasksHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := service.TaskListOptions{
+		UserID: currentUser(r),
+		Search: strings.TrimSpace(query.Get("search")),
+		Sort:   service.SortByDate,
+		Limit:  service.TaskQueryLimit,
+		Cursor: query.Get("cursor"),
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		if !service.IsValidTaskSort(sort) {
+			writeErrorResponse(w, http.StatusBadRequest, "Неподдерживаемая сортировка: "+sort)
+			return
+		}
+		opts.Sort = sort
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный порядок сортировки: "+order)
+		return
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			writeErrorResponse(w, http.StatusBadRequest, "Некорректный размер страницы")
+			return
+		}
+		opts.Limit = min(limit, service.TaskPageMaxLimit)
+	}
+	if err := parseTaskFilters(query, &opts); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if listStr := query.Get("list"); listStr != "" {
+		listID, err := strconv.ParseInt(listStr, 10, 64)
+		if err != nil || listID <= 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор списка")
+			return
+		}
+		if !h.requireListRole(w, r, listID, service.RoleViewer) {
+			return
+		}
+		opts.ListID = listID
+	}
+
+	page, err := h.TaskStore.ListTasks(opts)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный курсор")
+		return
+	} else if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
 		return
 	}
 
+	tasks := page.Tasks
 	if tasks == nil {
 		tasks = []model.Tasks{}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	response := map[string]interface{}{
 		"tasks": tasks,
-	})
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	if page.PrevCursor != "" {
+		response["prev_cursor"] = page.PrevCursor
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
+// PutTaskHandler сохраняет задачу целиком. Клиент передаёт версию, которую он редактировал,
+// в If-Match или в поле version: если задачу с тех пор изменили, ответ — 412.
 func (h *Handlers) PutTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireWriteAccess(w, r) {
+		return
+	}
 	var task model.Tasks
 	err := json.NewDecoder(r.Body).Decode(&task)
 	if err != nil {
@@ -145,10 +324,6 @@ func (h *Handlers) PutTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи")
 		return
 	}
-	if task.Title == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Не указан заголовок задачи")
-		return
-	}
 
 	id, err := strconv.Atoi(task.ID)
 	if err != nil || id <= 0 || id > math.MaxInt32 {
@@ -156,27 +331,50 @@ func (h *Handlers) PutTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	now := time.Now()
-	task.Date, err = service.ValidateTaskDate(now, task.Date, task.Repeat)
-	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+	if err := service.ValidateTask(time.Now(), &task); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	stored, err := h.TaskStore.GetTaskByID(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	if !h.requireListRole(w, r, stored.ListID, service.RoleEditor) {
+		return
+	}
+	if !checkTaskVersion(w, r, stored, task.Version) {
 		return
 	}
 
-	_, err = h.TaskRepository.UpdateTask(task)
+	task.Version = stored.Version
+	affected, err := h.TaskStore.UpdateTask(currentActor(r), task)
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
 		return
 	}
+	if affected == 0 {
+		h.writeTaskChanged(w, r, id)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", taskETag(stored.Version+1))
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte("{}")); err != nil {
 		log.Printf("Ошибка записи ответа: %v", err)
 	}
 }
 
+// DeleteTaskHandler переносит задачу в корзину, откуда её можно восстановить через /api/trash/restore
 func (h *Handlers) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireWriteAccess(w, r) {
+		return
+	}
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
 		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи")
@@ -189,12 +387,26 @@ func (h *Handlers) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.TaskRepository.DeleteTask(id)
+	task, err := h.TaskStore.GetTaskWithArchived(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+	if !h.requireListRole(w, r, task.ListID, service.RoleEditor) {
+		return
+	}
+
+	_, err = h.TaskStore.DeleteTask(currentActor(r), id)
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
 		return
 	}
 
+	h.issueUndoToken(w, currentUser(r), service.UndoActionDelete, service.UndoSnapshot{Task: task, Version: task.Version + 1})
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte("{}")); err != nil {
@@ -202,7 +414,74 @@ func (h *Handlers) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// DoneTaskHandler отмечает задачу выполненной. Версию задачи клиент передаёт в If-Match
+// или в параметре version, как и при изменении через PUT /api/task.
 func (h *Handlers) DoneTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireWriteAccess(w, r) {
+		return
+	}
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи.")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор задачи.")
+		return
+	}
+	version, ok := parseVersion(r.URL.Query().Get("version"))
+	if !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректная версия задачи.")
+		return
+	}
+
+	task, err := h.TaskStore.GetTaskByID(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена.")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	if !h.requireListRole(w, r, task.ListID, service.RoleEditor) {
+		return
+	}
+	if !checkTaskVersion(w, r, task, version) {
+		return
+	}
+
+	now := time.Now()
+	completion := model.TaskCompletion{TaskID: task.ID, Date: task.Date, DoneAt: now.Format(time.RFC3339)}
+	snapshot := service.UndoSnapshot{Task: task, Completion: &completion, Version: task.Version + 1}
+	finished, err := h.TaskService.CompleteTask(now, &task)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка при расчете следующей даты: "+err.Error())
+		return
+	}
+
+	err = h.TaskStore.CompleteTask(currentActor(r), task, completion, finished)
+	if err == service.ErrVersionConflict {
+		h.writeTaskChanged(w, r, id)
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка обновления задачи: "+err.Error())
+		return
+	}
+
+	h.issueUndoToken(w, currentUser(r), service.UndoActionDone, snapshot)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", taskETag(task.Version+1))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("{}")); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
+
+func (h *Handlers) SkipTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireWriteAccess(w, r) {
+		return
+	}
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
 		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи.")
@@ -214,7 +493,7 @@ func (h *Handlers) DoneTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.TaskRepository.GetTaskByID(id)
+	task, err := h.TaskStore.GetTaskByID(currentUser(r), id)
 	if err == sql.ErrNoRows {
 		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена.")
 		return
@@ -222,23 +501,32 @@ func (h *Handlers) DoneTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
 		return
 	}
+	if !h.requireListRole(w, r, task.ListID, service.RoleEditor) {
+		return
+	}
+
+	exception, finished, err := h.TaskService.SkipTask(time.Now(), &task)
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		writeValidationError(w, err)
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка при расчете следующей даты: "+err.Error())
+		return
+	}
 
-	if task.Repeat == "" {
-		_, err = h.TaskRepository.DeleteTask(id)
+	if finished {
+		_, err = h.TaskStore.ArchiveTask(currentActor(r), id, time.Now().Format(time.RFC3339))
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка удаления задачи: "+err.Error())
+			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка обновления задачи: "+err.Error())
 			return
 		}
 	} else {
-		now := time.Now()
-		nextDate, err := h.TaskService.NextDate(now, task.Date, task.Repeat)
-		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка при расчете следующей даты: "+err.Error())
+		err = h.TaskStore.RescheduleTask(currentActor(r), task, exception)
+		if err == service.ErrVersionConflict {
+			h.writeTaskChanged(w, r, id)
 			return
-		}
-
-		_, err = h.TaskRepository.UpdateTaskDate(id, nextDate)
-		if err != nil {
+		} else if err != nil {
 			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка обновления задачи: "+err.Error())
 			return
 		}
@@ -250,3 +538,219 @@ func (h *Handlers) DoneTaskHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Ошибка записи ответа: %v", err)
 	}
 }
+
+func (h *Handlers) MoveTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireWriteAccess(w, r) {
+		return
+	}
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи.")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор задачи.")
+		return
+	}
+
+	task, err := h.TaskStore.GetTaskByID(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена.")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	if !h.requireListRole(w, r, task.ListID, service.RoleEditor) {
+		return
+	}
+
+	exception, err := h.TaskService.MoveTask(time.Now(), &task, r.URL.Query().Get("date"))
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		writeValidationError(w, err)
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка при расчете следующей даты: "+err.Error())
+		return
+	}
+
+	err = h.TaskStore.RescheduleTask(currentActor(r), task, exception)
+	if err == service.ErrVersionConflict {
+		h.writeTaskChanged(w, r, id)
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка обновления задачи: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("{}")); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
+
+func (h *Handlers) GetTaskExceptionsHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор задачи")
+		return
+	}
+
+	if _, err := h.TaskStore.GetTaskWithArchived(currentUser(r), id); err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+
+	exceptions, err := h.TaskStore.GetTaskExceptions(currentUser(r), id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+
+	if exceptions == nil {
+		exceptions = []model.TaskException{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"exceptions": exceptions,
+	})
+}
+
+func (h *Handlers) GetTaskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор задачи")
+		return
+	}
+
+	task, err := h.TaskStore.GetTaskWithArchived(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+
+	completions, err := h.TaskStore.GetTaskCompletions(currentUser(r), id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+
+	if completions == nil {
+		completions = []model.TaskCompletion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"task":        task,
+		"completions": completions,
+	})
+}
+
+// issueUndoToken сохраняет снимок задачи и передаёт токен отмены в заголовке ответа.
+// Тело ответа не меняется, поэтому клиенты, которые не используют отмену, ничего не заметят.
+// Если токен сохранить не удалось, операция всё равно считается выполненной.
+func (h *Handlers) issueUndoToken(w http.ResponseWriter, userID int64, action string, snapshot service.UndoSnapshot) {
+	token, err := service.NewUndoToken()
+	if err != nil {
+		log.Printf("Ошибка создания токена отмены: %v", err)
+		return
+	}
+	if err := h.TaskStore.SaveUndo(userID, token, action, snapshot, time.Now().Add(h.UndoWindow)); err != nil {
+		log.Printf("Ошибка сохранения токена отмены: %v", err)
+		return
+	}
+	w.Header().Set(service.UndoTokenHeader, token)
+}
+
+// UndoHandler возвращает задачу в состояние до выполнения или удаления. Токен отмены, который
+// /api/task/done и DELETE /api/task вернули в заголовке X-Undo-Token, передаётся в параметре
+// token или в том же заголовке запроса.
+func (h *Handlers) UndoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+	if !requireWriteAccess(w, r) {
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = r.Header.Get(service.UndoTokenHeader)
+	}
+	if token == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан токен отмены")
+		return
+	}
+
+	task, err := h.TaskStore.Undo(currentActor(r), token, time.Now())
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Отмена недоступна: токен не найден, срок отмены истёк или задачу больше нельзя менять")
+		return
+	} else if err == service.ErrVersionConflict {
+		writeVersionConflict(w, task)
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка отмены: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.TaskResponse{ID: task.ID})
+}
+
+// parseTaskFilters разбирает фильтры списка задач: from, to, overdue и repeating
+func parseTaskFilters(query url.Values, opts *service.TaskListOptions) error {
+	for _, param := range []struct {
+		name  string
+		value *string
+	}{{"from", &opts.From}, {"to", &opts.To}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(service.DateFormat, value); err != nil {
+			return fmt.Errorf("Некорректная дата в параметре %s: %s", param.name, value)
+		}
+		*param.value = value
+	}
+	if opts.From != "" && opts.To != "" && opts.From > opts.To {
+		return errors.New("Дата from не может быть позже даты to")
+	}
+
+	if value := query.Get("overdue"); value != "" {
+		overdue, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("Некорректное значение параметра overdue: %s", value)
+		}
+		opts.Overdue = overdue
+		opts.Today = time.Now().Format(service.DateFormat)
+	}
+	if value := query.Get("repeating"); value != "" {
+		repeating, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("Некорректное значение параметра repeating: %s", value)
+		}
+		opts.Repeating = &repeating
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"go_final_project/model"
+	"go_final_project/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Эти тесты проверяют обработчики без сервера и базы: данные хранятся в service.MemoryStore.
+// Поведение API целиком проверяют тесты из каталога tests на запущенном сервере.
+
+func newTestHandlers(t *testing.T) (*Handlers, *service.MemoryStore) {
+	auth, err := service.NewAuthenticator("", "")
+	require.NoError(t, err)
+	store := service.NewMemoryStore()
+	return NewHandlersWithStores(store, store, store, auth), store
+}
+
+// credentials — как запрос представляется: cookie с токеном входа или личный токен
+type credentials struct {
+	cookie string
+	bearer string
+}
+
+// call выполняет обработчик через RequireAuth и возвращает код ответа и разобранный JSON
+func call(t *testing.T, h *Handlers, handler http.HandlerFunc, method string, target string, body any, as credentials) (int, map[string]any, http.Header) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	if as.cookie != "" {
+		req.AddCookie(&http.Cookie{Name: TokenCookie, Value: as.cookie})
+	}
+	if as.bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+as.bearer)
+	}
+	rec := httptest.NewRecorder()
+	h.RequireAuth(handler)(rec, req)
+
+	var m map[string]any
+	if rec.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m), rec.Body.String())
+	}
+	return rec.Code, m, rec.Header()
+}
+
+func newUser(t *testing.T, h *Handlers, store *service.MemoryStore, login string) (int64, credentials) {
+	t.Helper()
+	id, err := store.CreateUser(login, "hash-"+login)
+	require.NoError(t, err)
+	user, err := store.GetUserByID(id)
+	require.NoError(t, err)
+	token, err := h.Auth.UserToken(user, time.Now())
+	require.NoError(t, err)
+	return id, credentials{cookie: token}
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	today := time.Now().Format(service.DateFormat)
+
+	code, ret, _ := call(t, h, h.PostTaskHandler, http.MethodPost, "/api/task", map[string]any{
+		"date": today, "title": "Полить цветы", "repeat": "d 2",
+	}, credentials{})
+	require.Equal(t, http.StatusOK, code, ret)
+	id := ret["id"].(string)
+
+	code, ret, _ = call(t, h, h.PostTaskHandler, http.MethodPost, "/api/task", map[string]any{"date": today}, credentials{})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "title", ret["field"])
+
+	code, ret, header := call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task?id="+id, nil, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Полить цветы", ret["title"])
+	assert.Equal(t, `"1"`, header.Get("ETag"))
+
+	edit := map[string]any{"id": id, "date": today, "title": "Полить фикус", "repeat": "d 2", "version": ret["version"]}
+	code, _, header = call(t, h, h.PutTaskHandler, http.MethodPut, "/api/task", edit, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `"2"`, header.Get("ETag"))
+
+	edit["title"] = "Полить кактус"
+	code, ret, _ = call(t, h, h.PutTaskHandler, http.MethodPut, "/api/task", edit, credentials{})
+	assert.Equal(t, http.StatusPreconditionFailed, code, "Изменение устаревшей версии не должно сохраниться")
+	assert.Equal(t, "Полить фикус", ret["task"].(map[string]any)["title"])
+	code, _, _ = call(t, h, h.DoneTaskHandler, http.MethodPost, "/api/task/done?id="+id, nil, credentials{})
+	assert.Equal(t, http.StatusPreconditionRequired, code)
+
+	code, _, header = call(t, h, h.DoneTaskHandler, http.MethodPost, "/api/task/done?id="+id+"&version=2", nil, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+	_, ret, _ = call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task?id="+id, nil, credentials{})
+	assert.Equal(t, time.Now().AddDate(0, 0, 2).Format(service.DateFormat), ret["date"])
+	assert.Equal(t, "Полить фикус", ret["title"])
+
+	code, ret, _ = call(t, h, h.UndoHandler, http.MethodPost, "/api/undo?token="+header.Get(service.UndoTokenHeader), nil, credentials{})
+	assert.Equal(t, http.StatusOK, code, ret)
+	_, ret, _ = call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task?id="+id, nil, credentials{})
+	assert.Equal(t, today, ret["date"])
+	_, ret, _ = call(t, h, h.GetTaskHistoryHandler, http.MethodGet, "/api/task/history?id="+id, nil, credentials{})
+	assert.Empty(t, ret["completions"])
+
+	code, _, header = call(t, h, h.DeleteTaskHandler, http.MethodDelete, "/api/task?id="+id, nil, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task?id="+id, nil, credentials{})
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = call(t, h, h.UndoHandler, http.MethodPost, "/api/undo?token="+header.Get(service.UndoTokenHeader), nil, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task?id="+id, nil, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestTasksPages(t *testing.T) {
+	h, store := newTestHandlers(t)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := store.CreateTask(service.Actor{}, model.Tasks{Date: start.AddDate(0, 0, i).Format(service.DateFormat), Title: "Задача " + strconv.Itoa(i)})
+		require.NoError(t, err)
+	}
+
+	var titles []any
+	target := "/api/tasks?limit=2"
+	for pages := 0; pages < 5; pages++ {
+		code, ret, _ := call(t, h, h.GetTasksHandler, http.MethodGet, target, nil, credentials{})
+		require.Equal(t, http.StatusOK, code, ret)
+		for _, task := range ret["tasks"].([]any) {
+			titles = append(titles, task.(map[string]any)["title"])
+		}
+		next, ok := ret["next_cursor"].(string)
+		if !ok {
+			break
+		}
+		target = "/api/tasks?limit=2&cursor=" + next
+	}
+	assert.Equal(t, []any{"Задача 0", "Задача 1", "Задача 2", "Задача 3", "Задача 4"}, titles)
+
+	_, ret, _ := call(t, h, h.GetTasksHandler, http.MethodGet, "/api/tasks?search=задача+3", nil, credentials{})
+	assert.Len(t, ret["tasks"], 1)
+	code, _, _ := call(t, h, h.GetTasksHandler, http.MethodGet, "/api/tasks?sort=title&cursor=broken", nil, credentials{})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestListPermissions(t *testing.T) {
+	h, store := newTestHandlers(t)
+	_, owner := newUser(t, h, store, "owner")
+	_, viewer := newUser(t, h, store, "viewer")
+	_, stranger := newUser(t, h, store, "stranger")
+
+	code, ret, _ := call(t, h, h.ListsHandler, http.MethodPost, "/api/lists", map[string]any{"name": "Дом"}, owner)
+	require.Equal(t, http.StatusCreated, code, ret)
+	list := ret["id"].(string)
+	code, _, _ = call(t, h, h.ListMembersHandler, http.MethodPost, "/api/lists/members", map[string]any{
+		"list_id": list, "login": "viewer", "role": service.RoleViewer,
+	}, owner)
+	require.Equal(t, http.StatusOK, code)
+
+	today := time.Now().Format(service.DateFormat)
+	code, ret, _ = call(t, h, h.PostTaskHandler, http.MethodPost, "/api/task", map[string]any{
+		"date": today, "title": "Вынести мусор", "list_id": list,
+	}, owner)
+	require.Equal(t, http.StatusOK, code, ret)
+	id := ret["id"].(string)
+
+	code, _, _ = call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task?id="+id, nil, viewer)
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = call(t, h, h.DoneTaskHandler, http.MethodPost, "/api/task/done?id="+id, nil, viewer)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = call(t, h, h.DeleteTaskHandler, http.MethodDelete, "/api/task?id="+id, nil, viewer)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task?id="+id, nil, stranger)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = call(t, h, h.GetTasksHandler, http.MethodGet, "/api/tasks?list="+list, nil, stranger)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = call(t, h, h.DoneTaskHandler, http.MethodPost, "/api/task/done?id="+id+"&version=1", nil, owner)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestReadOnlyToken(t *testing.T) {
+	h, store := newTestHandlers(t)
+	userID, _ := newUser(t, h, store, "reader")
+	plain, hash, err := service.NewAPIToken()
+	require.NoError(t, err)
+	_, err = store.CreateAPIToken(userID, model.APIToken{Name: "Дашборд", Scope: service.ScopeRead}, hash)
+	require.NoError(t, err)
+	reader := credentials{bearer: plain}
+
+	code, _, _ := call(t, h, h.GetTasksHandler, http.MethodGet, "/api/tasks", nil, reader)
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = call(t, h, h.PostTaskHandler, http.MethodPost, "/api/task", map[string]any{
+		"date": time.Now().Format(service.DateFormat), "title": "Нельзя",
+	}, reader)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = call(t, h, h.APITokensHandler, http.MethodGet, "/api/tokens", nil, reader)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = call(t, h, h.GetTasksHandler, http.MethodGet, "/api/tasks", nil, credentials{bearer: "todo_unknown"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestTrash(t *testing.T) {
+	h, store := newTestHandlers(t)
+	id, err := store.CreateTask(service.Actor{}, model.Tasks{Date: time.Now().Format(service.DateFormat), Title: "Старая задача"})
+	require.NoError(t, err)
+	target := "?id=" + strconv.FormatInt(id, 10)
+
+	code, _, _ := call(t, h, h.DeleteTaskHandler, http.MethodDelete, "/api/task"+target, nil, credentials{})
+	require.Equal(t, http.StatusOK, code)
+	_, ret, _ := call(t, h, h.TrashHandler, http.MethodGet, "/api/trash", nil, credentials{})
+	assert.Len(t, ret["tasks"], 1)
+
+	code, _, _ = call(t, h, h.RestoreTaskHandler, http.MethodPost, "/api/trash/restore"+target, nil, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = call(t, h, h.GetTaskHandler, http.MethodGet, "/api/task"+target, nil, credentials{})
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = call(t, h, h.RestoreTaskHandler, http.MethodPost, "/api/trash/restore"+target, nil, credentials{})
+	assert.Equal(t, http.StatusNotFound, code)
+	_, ret, _ = call(t, h, h.TaskAuditHandler, http.MethodGet, "/api/task/audit"+target, nil, credentials{})
+	if entries := ret["entries"].([]any); assert.Len(t, entries, 3) {
+		assert.Equal(t, service.AuditActionRestore, entries[0].(map[string]any)["action"])
+	}
+
+	code, _, _ = call(t, h, h.DeleteTaskHandler, http.MethodDelete, "/api/task"+target, nil, credentials{})
+	require.Equal(t, http.StatusOK, code)
+	purged, err := store.PurgeTrash(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged, "Задачи моложе срока хранения остаются в корзине")
+	purged, err = store.PurgeTrash(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+	page, err := store.GetAuditLog(service.AuditFilter{TaskID: id, Action: service.AuditActionPurge, Limit: 10})
+	require.NoError(t, err)
+	if assert.Len(t, page.Entries, 1, "Удаление по сроку хранения тоже попадает в журнал") {
+		assert.Equal(t, service.TrashRetentionRequestID, page.Entries[0].RequestID)
+		assert.Nil(t, page.Entries[0].After)
+	}
+	_, ret, _ = call(t, h, h.TrashHandler, http.MethodGet, "/api/trash", nil, credentials{})
+	assert.Empty(t, ret["tasks"])
+}
+
+func TestUndo(t *testing.T) {
+	h, store := newTestHandlers(t)
+	_, owner := newUser(t, h, store, "owner")
+	_, editor := newUser(t, h, store, "editor")
+	_, ret, _ := call(t, h, h.ListsHandler, http.MethodPost, "/api/lists", map[string]any{"name": "Дом"}, owner)
+	list := ret["id"].(string)
+	setRole := func(role string) {
+		code, _, _ := call(t, h, h.ListMembersHandler, http.MethodPost, "/api/lists/members", map[string]any{
+			"list_id": list, "login": "editor", "role": role,
+		}, owner)
+		require.Equal(t, http.StatusOK, code)
+	}
+	setRole(service.RoleEditor)
+
+	code, ret, _ := call(t, h, h.PostTaskHandler, http.MethodPost, "/api/task", map[string]any{
+		"date": time.Now().Format(service.DateFormat), "title": "Вынести мусор", "list_id": list,
+	}, owner)
+	require.Equal(t, http.StatusOK, code, ret)
+	target := "?id=" + ret["id"].(string)
+
+	code, _, header := call(t, h, h.DeleteTaskHandler, http.MethodDelete, "/api/task"+target, nil, editor)
+	require.Equal(t, http.StatusOK, code)
+	token := header.Get(service.UndoTokenHeader)
+	setRole(service.RoleViewer)
+	code, _, _ = call(t, h, h.UndoHandler, http.MethodPost, "/api/undo?token="+token, nil, editor)
+	assert.Equal(t, http.StatusNotFound, code, "Без права менять задачу отмена недоступна")
+	setRole(service.RoleEditor)
+	code, _, _ = call(t, h, h.UndoHandler, http.MethodPost, "/api/undo?token="+token, nil, editor)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _, header = call(t, h, h.DeleteTaskHandler, http.MethodDelete, "/api/task"+target, nil, editor)
+	require.Equal(t, http.StatusOK, code)
+	token = header.Get(service.UndoTokenHeader)
+	code, _, _ = call(t, h, h.RestoreTaskHandler, http.MethodPost, "/api/trash/restore"+target, nil, owner)
+	require.Equal(t, http.StatusOK, code)
+	code, ret, _ = call(t, h, h.UndoHandler, http.MethodPost, "/api/undo?token="+token, nil, editor)
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	assert.Equal(t, "Вынести мусор", ret["task"].(map[string]any)["title"])
+}
+
+func TestAuditLog(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ownerID, owner := newUser(t, h, store, "owner")
+	_, editor := newUser(t, h, store, "editor")
+	_, stranger := newUser(t, h, store, "stranger")
+
+	_, ret, _ := call(t, h, h.ListsHandler, http.MethodPost, "/api/lists", map[string]any{"name": "Дача"}, owner)
+	list := ret["id"].(string)
+	code, _, _ := call(t, h, h.ListMembersHandler, http.MethodPost, "/api/lists/members", map[string]any{
+		"list_id": list, "login": "editor", "role": service.RoleEditor,
+	}, owner)
+	require.Equal(t, http.StatusOK, code)
+
+	today := time.Now().Format(service.DateFormat)
+	code, ret, header := call(t, h, h.PostTaskHandler, http.MethodPost, "/api/task", map[string]any{
+		"date": today, "title": "Покрасить забор", "list_id": list,
+	}, owner)
+	require.Equal(t, http.StatusOK, code, ret)
+	id := ret["id"].(string)
+	assert.NotEmpty(t, header.Get(service.RequestIDHeader))
+	code, _, _ = call(t, h, h.DoneTaskHandler, http.MethodPost, "/api/task/done?id="+id+"&version=1", nil, editor)
+	require.Equal(t, http.StatusOK, code)
+
+	_, ret, _ = call(t, h, h.TaskAuditHandler, http.MethodGet, "/api/task/audit?id="+id, nil, owner)
+	entries := ret["entries"].([]any)
+	require.Len(t, entries, 2)
+	done := entries[0].(map[string]any)
+	assert.Equal(t, service.AuditActionDone, done["action"])
+	assert.NotEqual(t, strconv.FormatInt(ownerID, 10), done["user_id"], "Действие записывается на того, кто его выполнил")
+	assert.Equal(t, header.Get(service.RequestIDHeader), entries[1].(map[string]any)["request_id"])
+
+	_, ret, _ = call(t, h, h.AuditHandler, http.MethodGet, "/api/audit?list="+list+"&user="+strconv.FormatInt(ownerID, 10), nil, editor)
+	assert.Len(t, ret["entries"], 1)
+	code, _, _ = call(t, h, h.AuditHandler, http.MethodGet, "/api/audit?list="+list, nil, stranger)
+	assert.Equal(t, http.StatusNotFound, code)
+	_, ret, _ = call(t, h, h.AuditHandler, http.MethodGet, "/api/audit", nil, stranger)
+	assert.Empty(t, ret["entries"])
+}
+
+func TestPatchTask(t *testing.T) {
+	h, store := newTestHandlers(t)
+	id, err := store.CreateTask(service.Actor{}, model.Tasks{Date: "20240105", Title: "Заменить фильтр", Comment: "В ванной"})
+	require.NoError(t, err)
+	target := "/api/task?id=" + strconv.FormatInt(id, 10)
+
+	code, ret, header := call(t, h, h.PatchTaskHandler, http.MethodPatch, target, map[string]any{"title": "Заменить фильтр для воды", "version": "1"}, credentials{})
+	require.Equal(t, http.StatusOK, code, ret)
+	assert.Equal(t, "Заменить фильтр для воды", ret["title"])
+	assert.Equal(t, "В ванной", ret["comment"], "Непереданные поля не меняются")
+	assert.Equal(t, "20240105", ret["date"], "Дата не пересчитывается, если не менялась")
+	assert.Equal(t, `"2"`, header.Get("ETag"))
+
+	code, ret, _ = call(t, h, h.PatchTaskHandler, http.MethodPatch, target, map[string]any{"date": "20240105", "version": "1"}, credentials{})
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	assert.Equal(t, "Заменить фильтр для воды", ret["task"].(map[string]any)["title"])
+	code, ret, _ = call(t, h, h.PatchTaskHandler, http.MethodPatch, target, map[string]any{"title": nil, "version": "2"}, credentials{})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "title", ret["field"])
+	code, _, _ = call(t, h, h.PatchTaskHandler, http.MethodPatch, target, []string{"title"}, credentials{})
+	assert.Equal(t, http.StatusBadRequest, code, "Тело PATCH должно быть объектом")
+
+	page, err := store.GetAuditLog(service.AuditFilter{TaskID: id, Action: service.AuditActionUpdate, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, page.Entries, 1)
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go_final_project/model"
+	"go_final_project/service"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// requireListRole проверяет, что у пользователя запроса в списке listID есть роль не ниже required.
+// Если он в списке не состоит, отвечает 404, как будто списка нет, если роль ниже — 403.
+// Для личных задач (listID = 0) проверять нечего.
+func (h *Handlers) requireListRole(w http.ResponseWriter, r *http.Request, listID int64, required string) bool {
+	if listID == 0 {
+		return true
+	}
+	role, err := h.ListStore.GetRole(currentUser(r), listID)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Список не найден")
+		return false
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return false
+	}
+	if !service.RoleAllows(role, required) {
+		writeErrorResponse(w, http.StatusForbidden, "Недостаточно прав: нужна роль "+required)
+		return false
+	}
+	return true
+}
+
+// requireUser отвечает 403, если запрос выполняется без входа по логину: у общего списка
+// задач нет пользователя, которого можно было бы добавить в список
+func requireUser(w http.ResponseWriter, r *http.Request) bool {
+	if currentUser(r) == 0 {
+		writeErrorResponse(w, http.StatusForbidden, "Списки доступны только после входа по логину")
+		return false
+	}
+	return true
+}
+
+func parseListID(value string) (int64, bool) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	return id, err == nil && id > 0
+}
+
+// ListsHandler — GET возвращает списки пользователя с его ролью в каждом,
+// POST {"name": "..."} создаёт список, владельцем которого становится пользователь
+func (h *Handlers) ListsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireUser(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		lists, err := h.ListStore.GetLists(currentUser(r))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+			return
+		}
+		if lists == nil {
+			lists = []model.List{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lists": lists,
+		})
+	case http.MethodPost:
+		if !requireWriteAccess(w, r) {
+			return
+		}
+		var list model.List
+		if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+			return
+		}
+		if err := service.ValidateList(&list); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		list, err := h.ListStore.CreateList(currentUser(r), list)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(list)
+	default:
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// ListMembersHandler управляет участниками списка: GET ?list= — участники (видят все участники),
+// POST {"list_id", "login", "role"} — приглашение или смена роли, DELETE ?list=&user= — исключение.
+// Приглашать и исключать может владелец; любой участник может сам выйти из списка.
+func (h *Handlers) ListMembersHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireUser(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getListMembers(w, r)
+	case http.MethodPost:
+		if requireWriteAccess(w, r) {
+			h.inviteListMember(w, r)
+		}
+	case http.MethodDelete:
+		if requireWriteAccess(w, r) {
+			h.removeListMember(w, r)
+		}
+	default:
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+func (h *Handlers) getListMembers(w http.ResponseWriter, r *http.Request) {
+	listID, ok := parseListID(r.URL.Query().Get("list"))
+	if !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор списка")
+		return
+	}
+	if !h.requireListRole(w, r, listID, service.RoleViewer) {
+		return
+	}
+
+	members, err := h.ListStore.GetMembers(listID)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"members": members,
+	})
+}
+
+func (h *Handlers) inviteListMember(w http.ResponseWriter, r *http.Request) {
+	var invitation model.ListInvitation
+	if err := json.NewDecoder(r.Body).Decode(&invitation); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+		return
+	}
+	listID, ok := parseListID(invitation.ListID)
+	if !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор списка")
+		return
+	}
+	if err := service.ValidateInvitation(&invitation); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !h.requireListRole(w, r, listID, service.RoleOwner) {
+		return
+	}
+
+	user, err := h.UserStore.GetUserByLogin(invitation.Login)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Пользователь не найден")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(user.ID, 10, 64)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Некорректный идентификатор пользователя: "+user.ID)
+		return
+	}
+
+	err = h.ListStore.SetMember(listID, userID, invitation.Role)
+	if err == service.ErrLastOwner {
+		writeErrorResponse(w, http.StatusConflict, "В списке должен остаться хотя бы один владелец")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("{}")); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
+
+func (h *Handlers) removeListMember(w http.ResponseWriter, r *http.Request) {
+	listID, ok := parseListID(r.URL.Query().Get("list"))
+	if !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор списка")
+		return
+	}
+	userID, ok := parseListID(r.URL.Query().Get("user"))
+	if !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор пользователя")
+		return
+	}
+	required := service.RoleOwner
+	if userID == currentUser(r) {
+		required = service.RoleViewer
+	}
+	if !h.requireListRole(w, r, listID, required) {
+		return
+	}
+
+	err := h.ListStore.RemoveMember(listID, userID)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Пользователь не состоит в списке")
+		return
+	} else if err == service.ErrLastOwner {
+		writeErrorResponse(w, http.StatusConflict, "В списке должен остаться хотя бы один владелец")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("{}")); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
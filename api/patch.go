@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go_final_project/model"
+	"go_final_project/service"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MergePatchContentType — тип тела PATCH /api/task по RFC 7396; обычный application/json тоже принимается
+const MergePatchContentType = "application/merge-patch+json"
+
+// patchableFields — поля задачи, которые можно изменить через PATCH. Остальные ведёт сервер.
+var patchableFields = map[string]bool{
+	"date":      true,
+	"title":     true,
+	"comment":   true,
+	"repeat":    true,
+	"end_date":  true,
+	"max_count": true,
+}
+
+// PatchTaskHandler — PATCH /api/task?id= меняет только переданные поля задачи по правилам
+// JSON Merge Patch: поле со значением null очищается, отсутствующее остаётся как есть.
+// Версию задачи клиент передаёт в If-Match или в поле version, как и в PUT /api/task.
+// В ответе — задача после изменения.
+func (h *Handlers) PatchTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireWriteAccess(w, r) {
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != MergePatchContentType && mediaType != "application/json" {
+			writeErrorResponse(w, http.StatusUnsupportedMediaType, "Ожидается тело в формате "+MergePatchContentType)
+			return
+		}
+	}
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор задачи")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка чтения запроса: "+err.Error())
+		return
+	}
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+		return
+	}
+	if patch == nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Изменения задачи передаются JSON-объектом")
+		return
+	}
+	// version — не изменение, а версия задачи, которую видел клиент
+	var precondition struct {
+		Version int64 `json:"version,omitempty,string"`
+	}
+	if err := json.Unmarshal(body, &precondition); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректная версия задачи")
+		return
+	}
+	delete(patch, "version")
+	for field := range patch {
+		if !patchableFields[field] {
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Поле %s нельзя изменить", field))
+			return
+		}
+	}
+
+	stored, err := h.TaskStore.GetTaskByID(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	if !h.requireListRole(w, r, stored.ListID, service.RoleEditor) {
+		return
+	}
+	if !checkTaskVersion(w, r, stored, precondition.Version) {
+		return
+	}
+
+	task, err := mergeTaskPatch(stored, patch)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+		return
+	}
+	if err := service.ValidateTaskPatch(time.Now(), stored, &task); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	affected, err := h.TaskStore.UpdateTask(currentActor(r), task)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+	if affected == 0 {
+		h.writeTaskChanged(w, r, id)
+		return
+	}
+
+	updated, err := h.TaskStore.GetTaskByID(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", taskETag(updated.Version))
+	json.NewEncoder(w).Encode(updated)
+}
+
+// mergeTaskPatch применяет patch к задаче stored. У задачи нет вложенных объектов, поэтому
+// слияние по RFC 7396 сводится к замене полей верхнего уровня и удалению полей со значением null.
+func mergeTaskPatch(stored model.Tasks, patch map[string]json.RawMessage) (model.Tasks, error) {
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return model.Tasks{}, err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return model.Tasks{}, err
+	}
+	for field, value := range patch {
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			delete(merged, field)
+		} else {
+			merged[field] = value
+		}
+	}
+
+	if data, err = json.Marshal(merged); err != nil {
+		return model.Tasks{}, err
+	}
+	var task model.Tasks
+	if err := json.Unmarshal(data, &task); err != nil {
+		return model.Tasks{}, err
+	}
+	task.ID, task.Version = stored.ID, stored.Version
+	return task, nil
+}
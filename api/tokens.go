@@ -0,0 +1,100 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go_final_project/model"
+	"go_final_project/service"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// APITokensHandler управляет личными токенами пользователя: GET — список, POST — новый токен,
+// DELETE ?id= — отзыв. Сами токены этим не пользуются: иначе токен только для чтения
+// мог бы выпустить себе токен с полным доступом.
+func (h *Handlers) APITokensHandler(w http.ResponseWriter, r *http.Request) {
+	if currentIdentity(r).APIToken {
+		writeErrorResponse(w, http.StatusForbidden, "Управлять токенами можно только после входа в приложение")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getAPITokens(w, r)
+	case http.MethodPost:
+		h.createAPIToken(w, r)
+	case http.MethodDelete:
+		h.deleteAPIToken(w, r)
+	default:
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+func (h *Handlers) getAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.UserStore.GetAPITokens(currentUser(r))
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+
+	if tokens == nil {
+		tokens = []model.APIToken{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tokens": tokens,
+	})
+}
+
+func (h *Handlers) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	var token model.APIToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Ошибка декодирования JSON: "+err.Error())
+		return
+	}
+	if err := service.ValidateAPIToken(&token); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	plain, hash, err := service.NewAPIToken()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка создания токена: "+err.Error())
+		return
+	}
+	token, err = h.UserStore.CreateAPIToken(currentUser(r), token, hash)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+	token.Token = plain
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *Handlers) deleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор токена")
+		return
+	}
+
+	err = h.UserStore.DeleteAPIToken(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Токен не найден")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("{}")); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go_final_project/model"
+	"go_final_project/service"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// TrashHandler — GET возвращает задачи из корзины, DELETE ?id= удаляет задачу из корзины окончательно
+func (h *Handlers) TrashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tasks, err := h.TaskStore.GetTrash(currentUser(r))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+			return
+		}
+		if tasks == nil {
+			tasks = []model.Tasks{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tasks": tasks,
+		})
+	case http.MethodDelete:
+		if requireWriteAccess(w, r) {
+			h.changeTrashedTask(w, r, h.TaskStore.PurgeTask)
+		}
+	default:
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+	}
+}
+
+// RestoreTaskHandler — POST ?id= возвращает задачу из корзины
+func (h *Handlers) RestoreTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		return
+	}
+	if requireWriteAccess(w, r) {
+		h.changeTrashedTask(w, r, h.TaskStore.RestoreTask)
+	}
+}
+
+// changeTrashedTask находит задачу из корзины по id из запроса, проверяет, что пользователь
+// может её менять, и применяет к ней change
+func (h *Handlers) changeTrashedTask(w http.ResponseWriter, r *http.Request, change func(actor service.Actor, id int) (int64, error)) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "Не указан идентификатор задачи")
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Некорректный идентификатор задачи")
+		return
+	}
+
+	task, err := h.TaskStore.GetTrashedTask(currentUser(r), id)
+	if err == sql.ErrNoRows {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена в корзине")
+		return
+	} else if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка выполнения запроса: "+err.Error())
+		return
+	}
+	if !h.requireListRole(w, r, task.ListID, service.RoleEditor) {
+		return
+	}
+
+	affected, err := change(currentActor(r), id)
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "Ошибка базы данных: "+err.Error())
+		return
+	}
+	if affected == 0 {
+		writeErrorResponse(w, http.StatusNotFound, "Задача не найдена в корзине")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("{}")); err != nil {
+		log.Printf("Ошибка записи ответа: %v", err)
+	}
+}
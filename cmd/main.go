@@ -1,76 +1,199 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"go_final_project/api"
+	"go_final_project/migrations"
+	"go_final_project/service"
 	"go_final_project/tests"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func InitDB() *sql.DB {
-	dbFile := os.Getenv("TODO_DBFILE")
-
-	if dbFile == "" {
-		appPath, err := os.Executable()
-		if err != nil {
-			log.Fatal("Ошибка получения пути к файлу:", err)
-		}
-		dbFile = filepath.Join(filepath.Dir(appPath), "scheduler.db")
+// dbPath возвращает путь к файлу базы: из TODO_DBFILE или scheduler.db рядом с приложением
+func dbPath() string {
+	if dbFile := os.Getenv("TODO_DBFILE"); dbFile != "" {
+		return dbFile
 	}
-
-	_, err := os.Stat(dbFile)
-
-	var install bool
+	appPath, err := os.Executable()
 	if err != nil {
-		install = true
+		log.Fatal("Ошибка получения пути к файлу:", err)
 	}
+	return filepath.Join(filepath.Dir(appPath), "scheduler.db")
+}
 
-	db, err := sql.Open("sqlite3", dbFile)
+// dataSource возвращает драйвер и строку подключения: PostgreSQL, если задана TODO_DATABASE_URL,
+// иначе файл SQLite
+func dataSource() (driver string, dsn string) {
+	if url := os.Getenv("TODO_DATABASE_URL"); url != "" {
+		return service.DriverPostgres, url
+	}
+	return service.DriverSQLite, dbPath()
+}
+
+// InitDB открывает базу и применяет к ней миграции, которых в ней ещё нет. Новая база
+// создаётся теми же миграциями. Если база обновлена более новой версией приложения,
+// сервер не запускается.
+func InitDB() *service.DB {
+	driver, dsn := dataSource()
+	db, err := service.Open(driver, dsn)
 	if err != nil {
 		log.Fatal("Ошибка при открытии базы данных:", err)
 	}
+	if err := db.Ping(); err != nil {
+		log.Fatal("Ошибка подключения к базе данных:", err)
+	}
 
-	if install {
-		_, err = db.Exec(`
-			CREATE TABLE IF NOT EXISTS scheduler (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				date CHAR(8) NOT NULL DEFAULT "",
-				title VARCHAR(256) NOT NULL DEFAULT "",
-				comment TEXT,
-				repeat VARCHAR(128) NOT NULL DEFAULT ""
-			);
-			CREATE INDEX IF NOT EXISTS scheduler_date ON scheduler (date);
-		`)
+	applied, err := migrations.Apply(db, driver)
+	for _, m := range applied {
+		log.Printf("Применена миграция %s", m)
+	}
+	if errors.Is(err, migrations.ErrDatabaseNewer) {
+		log.Fatalf("%v. Запустите более новую версию приложения.", err)
+	} else if err != nil {
+		log.Fatal("Ошибка при обновлении структуры базы данных:", err)
+	}
+	return service.NewDB(db, driver)
+}
+
+// migrateCommand выводит состояние миграций и не меняет базу: status — список применённых
+// и ожидающих версий, dry-run — ещё и SQL, который будет выполнен при следующем запуске.
+// Возвращает код завершения.
+func migrateCommand(mode string) int {
+	if mode != "status" && mode != "dry-run" {
+		fmt.Fprintf(os.Stderr, "Неизвестный режим -migrate: %s, ожидается status или dry-run\n", mode)
+		return 2
+	}
+
+	driver, dsn := dataSource()
+	var status migrations.Status
+	var statusErr error
+	if _, err := os.Stat(dsn); err == nil || driver != service.DriverSQLite {
+		db, err := service.Open(driver, dsn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Ошибка при открытии базы данных:", err)
+			return 1
+		}
+		defer db.Close()
+		status, statusErr = migrations.GetStatus(db, driver)
+		if statusErr != nil && !errors.Is(statusErr, migrations.ErrDatabaseNewer) {
+			fmt.Fprintln(os.Stderr, "Ошибка чтения миграций:", statusErr)
+			return 1
+		}
+	} else {
+		all, err := migrations.Load(driver)
 		if err != nil {
-			log.Fatal("Ошибка при создании таблицы:", err)
+			fmt.Fprintln(os.Stderr, "Ошибка чтения миграций:", err)
+			return 1
 		}
-		log.Println("База данных создана успешно.")
+		status.Pending = all
+		fmt.Printf("База данных %s ещё не создана\n", dsn)
+	}
+
+	fmt.Printf("Версия схемы приложения: %d\n", migrations.Latest(driver))
+	for _, a := range status.Applied {
+		fmt.Printf("  %s применена %s\n", a, a.AppliedAt)
+	}
+	for _, m := range status.Pending {
+		fmt.Printf("  %s ожидает применения\n", m)
+		if mode == "dry-run" {
+			fmt.Println(m.SQL)
+		}
+	}
+	if statusErr != nil {
+		fmt.Fprintln(os.Stderr, statusErr)
+		return 1
+	}
+	if len(status.Pending) == 0 {
+		fmt.Println("Все миграции применены")
+	}
+	return 0
+}
 
+// purgeTrash окончательно удаляет задачи, которые пролежали в корзине дольше retention:
+// сразу при запуске и затем раз в TrashPurgeInterval
+func purgeTrash(store service.TaskStore, retention time.Duration) {
+	ticker := time.NewTicker(service.TrashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		purged, err := store.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Ошибка очистки корзины: %v", err)
+		} else if purged > 0 {
+			log.Printf("Из корзины окончательно удалено задач: %d", purged)
+		}
+		<-ticker.C
 	}
-	return db
 }
 
 func main() {
+	migrate := flag.String("migrate", "", "показать состояние миграций и выйти: status или dry-run")
+	flag.Parse()
+	if *migrate != "" {
+		os.Exit(migrateCommand(*migrate))
+	}
+
 	webDir := "./web"
 
 	db := InitDB()
 	defer db.Close()
 
-	handlers := api.NewHandlers(db)
+	auth, err := service.NewAuthenticator(os.Getenv("TODO_PASSWORD"), os.Getenv("TODO_JWT_SECRET"))
+	if err != nil {
+		log.Fatal("Ошибка настройки входа:", err)
+	}
+	handlers := api.NewHandlers(db, auth)
+	if envUndoWindow := os.Getenv("TODO_UNDO_WINDOW"); envUndoWindow != "" {
+		window, err := time.ParseDuration(envUndoWindow)
+		if err != nil || window <= 0 {
+			log.Fatalf("Некорректное значение переменной TODO_UNDO_WINDOW: %s. Завершение работы.", envUndoWindow)
+		}
+		handlers.UndoWindow = window
+	}
+
+	retention := service.DefaultTrashRetention
+	if envRetention := os.Getenv("TODO_TRASH_RETENTION"); envRetention != "" {
+		value, err := time.ParseDuration(envRetention)
+		if err != nil || value <= 0 {
+			log.Fatalf("Некорректное значение переменной TODO_TRASH_RETENTION: %s. Завершение работы.", envRetention)
+		}
+		retention = value
+	}
+	go purgeTrash(handlers.TaskStore, retention)
 
 	fileServer := http.FileServer(http.Dir(webDir))
 	http.Handle("/", fileServer)
 
 	http.HandleFunc("/api/nextdate", handlers.GetNextDateHandler)
-	http.HandleFunc("/api/tasks", handlers.GetTasksHandler)
-	http.HandleFunc("/api/task/done", handlers.DoneTaskHandler)
-	http.HandleFunc("/api/task", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/nextdate/preview", handlers.GetNextDatePreviewHandler)
+	http.HandleFunc("/api/repeat/describe", handlers.DescribeRepeatHandler)
+	http.HandleFunc("/api/signin", handlers.SigninHandler)
+	http.HandleFunc("/api/register", handlers.RegisterHandler)
+	http.HandleFunc("/api/login", handlers.LoginHandler)
+	http.HandleFunc("/api/tasks", handlers.RequireAuth(handlers.GetTasksHandler))
+	http.HandleFunc("/api/task/done", handlers.RequireAuth(handlers.DoneTaskHandler))
+	http.HandleFunc("/api/task/skip", handlers.RequireAuth(handlers.SkipTaskHandler))
+	http.HandleFunc("/api/task/move", handlers.RequireAuth(handlers.MoveTaskHandler))
+	http.HandleFunc("/api/task/exceptions", handlers.RequireAuth(handlers.GetTaskExceptionsHandler))
+	http.HandleFunc("/api/task/history", handlers.RequireAuth(handlers.GetTaskHistoryHandler))
+	http.HandleFunc("/api/undo", handlers.RequireAuth(handlers.UndoHandler))
+	http.HandleFunc("/api/audit", handlers.RequireAuth(handlers.AuditHandler))
+	http.HandleFunc("/api/task/audit", handlers.RequireAuth(handlers.TaskAuditHandler))
+	http.HandleFunc("/api/trash", handlers.RequireAuth(handlers.TrashHandler))
+	http.HandleFunc("/api/trash/restore", handlers.RequireAuth(handlers.RestoreTaskHandler))
+	http.HandleFunc("/api/tokens", handlers.RequireAuth(handlers.APITokensHandler))
+	http.HandleFunc("/api/lists", handlers.RequireAuth(handlers.ListsHandler))
+	http.HandleFunc("/api/lists/members", handlers.RequireAuth(handlers.ListMembersHandler))
+	http.HandleFunc("/api/task", handlers.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			handlers.GetTaskHandler(w, r)
@@ -78,12 +201,14 @@ func main() {
 			handlers.PostTaskHandler(w, r)
 		case http.MethodPut:
 			handlers.PutTaskHandler(w, r)
+		case http.MethodPatch:
+			handlers.PatchTaskHandler(w, r)
 		case http.MethodDelete:
 			handlers.DeleteTaskHandler(w, r)
 		default:
 			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		}
-	})
+	}))
 
 	defaultPort := tests.Port
 	envPort := os.Getenv("TODO_PORT")
@@ -99,7 +224,7 @@ func main() {
 
 	log.Printf("Сервер запущен на порте %d\n", port)
 
-	err := http.ListenAndServe(":"+strconv.Itoa(port), nil)
+	err = http.ListenAndServe(":"+strconv.Itoa(port), nil)
 	if err != nil {
 		log.Fatal("Ошибка запуска сервера:", err)
 	}
@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"database/sql"
+	"strings"
+)
+
+type column struct {
+	name       string
+	definition string
+}
+
+// legacyTables — столбцы, которые появлялись в таблицах до перехода на миграции. В базах тех лет
+// таблицы уже существуют, поэтому CREATE TABLE IF NOT EXISTS из 0001_baseline.sql их не трогает.
+var legacyTables = []struct {
+	name    string
+	columns []column
+}{
+	{"scheduler", []column{
+		{"end_date", `CHAR(8) NOT NULL DEFAULT ""`},
+		{"max_count", "INTEGER NOT NULL DEFAULT 0"},
+		{"done_count", "INTEGER NOT NULL DEFAULT 0"},
+		{"anchor_date", `CHAR(8) NOT NULL DEFAULT ""`},
+		{"completed_at", `VARCHAR(32) NOT NULL DEFAULT ""`},
+		{"created_at", `VARCHAR(32) NOT NULL DEFAULT ""`},
+		{"user_id", "INTEGER NOT NULL DEFAULT 0"},
+		{"list_id", "INTEGER NOT NULL DEFAULT 0"},
+	}},
+	{"undo_log", []column{
+		{"user_id", "INTEGER NOT NULL DEFAULT 0"},
+	}},
+}
+
+// legacyColumns добавляет недостающие столбцы в таблицы, созданные до перехода на миграции.
+// В новой базе таблиц ещё нет, и шаг ничего не делает.
+func legacyColumns(tx *sql.Tx) error {
+	for _, table := range legacyTables {
+		if err := addMissingColumns(tx, table.name, table.columns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addMissingColumns(tx *sql.Tx, table string, columns []column) error {
+	existing := make(map[string]bool)
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	for _, column := range columns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column.name + " " + column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyFullTextTriggers — триггеры индекса FTS5, который прежние версии создавали при запуске
+// сборки с тегом sqlite_fts5
+var legacyFullTextTriggers = []string{"scheduler_fts_insert", "scheduler_fts_delete", "scheduler_fts_update"}
+
+// dropFullTextFTS5 удаляет индекс FTS5 из прежних версий: в сборке без FTS5 его триггеры не дают
+// добавлять и менять задачи. Саму таблицу без модуля fts5 удалить нельзя, но без триггеров
+// она ничему не мешает, поэтому пропускается только эта ошибка.
+func dropFullTextFTS5(tx *sql.Tx) error {
+	for _, trigger := range legacyFullTextTriggers {
+		if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + trigger); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec("DROP TABLE IF EXISTS scheduler_fts")
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+// Package migrations хранит версии схемы базы данных и применяет их по порядку.
+// Каждая миграция — файл sqlite/NNNN_название.sql или postgres/NNNN_название.sql,
+// встроенный в приложение; версии у обеих баз общие. Применённые версии записываются
+// в таблицу schema_migrations.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+// Migration — одна версия схемы
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	// before — подготовка, которую нельзя записать на SQL; выполняется перед SQL в той же транзакции
+	before func(tx *sql.Tx) error
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// AppliedMigration — версия из schema_migrations и момент её применения. Name пусто,
+// если версия неизвестна этому приложению.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt string
+}
+
+func (a AppliedMigration) String() string {
+	if a.Name == "" {
+		return fmt.Sprintf("%04d", a.Version)
+	}
+	return fmt.Sprintf("%04d_%s", a.Version, a.Name)
+}
+
+// Status — применённые версии схемы и миграции, которые ещё предстоит применить
+type Status struct {
+	Applied []AppliedMigration
+	Pending []Migration
+}
+
+// ErrDatabaseNewer возвращается, если база уже обновлена более новой версией приложения,
+// чем запущенная: старый код может повредить данные в незнакомой ему схеме
+var ErrDatabaseNewer = errors.New("база данных создана более новой версией приложения")
+
+// dialects — каталог с миграциями для каждого драйвера database/sql
+var dialects = map[string]string{
+	"sqlite3":  "sqlite",
+	"postgres": "postgres",
+}
+
+// beforeSteps — шаги на Go для миграций SQLite, которым не хватает SQL. В PostgreSQL
+// баз, созданных до миграций, не бывает.
+var beforeSteps = map[int]func(tx *sql.Tx) error{
+	1: legacyColumns,
+	5: dropFullTextFTS5,
+}
+
+// Load возвращает все встроенные миграции для драйвера по возрастанию версии.
+// Версии должны идти подряд с 1.
+func Load(driver string) ([]Migration, error) {
+	dir, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("нет миграций для драйвера %s", driver)
+	}
+	names, err := fs.Glob(files, dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, name := range names {
+		base := strings.TrimSuffix(strings.TrimPrefix(name, dir+"/"), ".sql")
+		versionStr, title, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("некорректное имя файла миграции: %s", name)
+		}
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		m := Migration{Version: version, Name: title, SQL: string(data)}
+		if dir == "sqlite" {
+			m.before = beforeSteps[version]
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("миграции должны идти подряд: после версии %d найдена %s", i, m)
+		}
+	}
+	return migrations, nil
+}
+
+// Latest — версия схемы, которую ожидает приложение от базы с драйвером driver
+func Latest(driver string) int {
+	migrations, err := Load(driver)
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// GetStatus сравнивает версии из schema_migrations со встроенными миграциями и ничего не меняет в базе.
+// Если база новее приложения, вместе со статусом возвращается ErrDatabaseNewer.
+func GetStatus(db *sql.DB, driver string) (Status, error) {
+	migrations, err := Load(driver)
+	if err != nil {
+		return Status{}, err
+	}
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Applied: applied}
+	done := make(map[int]bool, len(applied))
+	for i, a := range applied {
+		done[a.Version] = true
+		if a.Version <= len(migrations) {
+			status.Applied[i].Name = migrations[a.Version-1].Name
+		}
+	}
+	for _, m := range migrations {
+		if !done[m.Version] {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+
+	if n := len(applied); n > 0 && applied[n-1].Version > len(migrations) {
+		return status, fmt.Errorf("%w: версия схемы %d, приложение поддерживает до %d",
+			ErrDatabaseNewer, applied[n-1].Version, len(migrations))
+	}
+	return status, nil
+}
+
+// Apply применяет ожидающие миграции по порядку, каждую в своей транзакции вместе с записью
+// в schema_migrations, и возвращает применённые. Если миграция не удалась, её изменения
+// откатываются, а следующие не выполняются.
+func Apply(db *sql.DB, driver string) ([]Migration, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at VARCHAR(32) NOT NULL
+	)`); err != nil {
+		return nil, err
+	}
+
+	status, err := GetStatus(db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range status.Pending {
+		if err := apply(db, driver, m); err != nil {
+			return applied, fmt.Errorf("миграция %s: %w", m, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+func apply(db *sql.DB, driver string, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if m.before != nil {
+		if err := m.before(tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return err
+	}
+	query := "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"
+	if driver == "postgres" {
+		query = "INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)"
+	}
+	if _, err := tx.Exec(query, m.Version, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedMigrations читает schema_migrations; если таблицы ещё нет, ни одна миграция не применена
+func appliedMigrations(db *sql.DB, driver string) ([]AppliedMigration, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'"
+	if driver == "postgres" {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'"
+	}
+	var count int
+	err := db.QueryRow(query).Scan(&count)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	for rows.Next() {
+		var a AppliedMigration
+		if err := rows.Scan(&a.Version, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,78 @@
+-- Та же схема, что и sqlite/0001_baseline.sql. Строки хранятся в TEXT и VARCHAR, а не в CHAR:
+-- CHAR в PostgreSQL дополняет значения пробелами. Идентификаторы — BIGSERIAL, чтобы отмена
+-- удаления могла вернуть задачу с прежним id.
+CREATE TABLE IF NOT EXISTS scheduler (
+	id BIGSERIAL PRIMARY KEY,
+	date VARCHAR(8) NOT NULL DEFAULT '',
+	title VARCHAR(256) NOT NULL DEFAULT '',
+	comment TEXT NOT NULL DEFAULT '',
+	repeat VARCHAR(128) NOT NULL DEFAULT '',
+	end_date VARCHAR(8) NOT NULL DEFAULT '',
+	max_count INTEGER NOT NULL DEFAULT 0,
+	done_count INTEGER NOT NULL DEFAULT 0,
+	anchor_date VARCHAR(8) NOT NULL DEFAULT '',
+	completed_at VARCHAR(32) NOT NULL DEFAULT '',
+	created_at VARCHAR(32) NOT NULL DEFAULT '',
+	user_id BIGINT NOT NULL DEFAULT 0,
+	list_id BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS scheduler_date ON scheduler (date);
+CREATE INDEX IF NOT EXISTS scheduler_user_date ON scheduler (user_id, date);
+CREATE INDEX IF NOT EXISTS scheduler_list_date ON scheduler (list_id, date);
+
+CREATE TABLE IF NOT EXISTS task_exceptions (
+	id BIGSERIAL PRIMARY KEY,
+	task_id BIGINT NOT NULL,
+	date VARCHAR(8) NOT NULL,
+	moved_to VARCHAR(8) NOT NULL DEFAULT '',
+	UNIQUE (task_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS task_completions (
+	id BIGSERIAL PRIMARY KEY,
+	task_id BIGINT NOT NULL,
+	date VARCHAR(8) NOT NULL,
+	done_at VARCHAR(32) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_completions_task ON task_completions (task_id);
+
+CREATE TABLE IF NOT EXISTS undo_log (
+	token VARCHAR(32) PRIMARY KEY,
+	action VARCHAR(16) NOT NULL,
+	task_id BIGINT NOT NULL,
+	snapshot TEXT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	user_id BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	login VARCHAR(64) NOT NULL UNIQUE,
+	password_hash VARCHAR(60) NOT NULL,
+	created_at VARCHAR(32) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	scope VARCHAR(16) NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	created_at VARCHAR(32) NOT NULL,
+	last_used_at VARCHAR(32) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS lists (
+	id BIGSERIAL PRIMARY KEY,
+	name VARCHAR(128) NOT NULL,
+	created_at VARCHAR(32) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS list_members (
+	list_id BIGINT NOT NULL,
+	user_id BIGINT NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	added_at VARCHAR(32) NOT NULL,
+	PRIMARY KEY (list_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS list_members_user ON list_members (user_id);
@@ -0,0 +1,25 @@
+-- Журнал изменений задач. Записи только добавляются: триггер не даёт их менять и удалять.
+-- user_id — кто выполнил действие, list_id — список задачи (0 у личных задач), task_before
+-- и task_after — задача в JSON до и после изменения (пусто, если задачи не было).
+CREATE TABLE audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	created_at VARCHAR(32) NOT NULL,
+	user_id BIGINT NOT NULL,
+	action VARCHAR(16) NOT NULL,
+	task_id BIGINT NOT NULL,
+	list_id BIGINT NOT NULL DEFAULT 0,
+	task_before TEXT NOT NULL DEFAULT '',
+	task_after TEXT NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT ''
+);
+CREATE INDEX audit_log_task ON audit_log (task_id, id);
+CREATE INDEX audit_log_user ON audit_log (user_id, id);
+CREATE INDEX audit_log_list ON audit_log (list_id, id);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'записи журнала аудита нельзя менять и удалять';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
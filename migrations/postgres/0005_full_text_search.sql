@@ -0,0 +1,2 @@
+-- Полнотекстовый индекс есть только в SQLite. В PostgreSQL поиск работает без него,
+-- а версия нужна, чтобы номера миграций у обеих баз совпадали.
@@ -0,0 +1,78 @@
+-- Схема на момент перехода на версионные миграции. Все таблицы создаются с IF NOT EXISTS:
+-- в базах, созданных до миграций, они уже есть, а недостающие столбцы добавляет шаг
+-- legacyColumns перед этим файлом.
+CREATE TABLE IF NOT EXISTS scheduler (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	date CHAR(8) NOT NULL DEFAULT "",
+	title VARCHAR(256) NOT NULL DEFAULT "",
+	comment TEXT,
+	repeat VARCHAR(128) NOT NULL DEFAULT "",
+	end_date CHAR(8) NOT NULL DEFAULT "",
+	max_count INTEGER NOT NULL DEFAULT 0,
+	done_count INTEGER NOT NULL DEFAULT 0,
+	anchor_date CHAR(8) NOT NULL DEFAULT "",
+	completed_at VARCHAR(32) NOT NULL DEFAULT "",
+	created_at VARCHAR(32) NOT NULL DEFAULT "",
+	user_id INTEGER NOT NULL DEFAULT 0,
+	list_id INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS scheduler_date ON scheduler (date);
+CREATE INDEX IF NOT EXISTS scheduler_user_date ON scheduler (user_id, date);
+CREATE INDEX IF NOT EXISTS scheduler_list_date ON scheduler (list_id, date);
+
+CREATE TABLE IF NOT EXISTS task_exceptions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	date CHAR(8) NOT NULL,
+	moved_to CHAR(8) NOT NULL DEFAULT "",
+	UNIQUE (task_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS task_completions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	date CHAR(8) NOT NULL,
+	done_at VARCHAR(32) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_completions_task ON task_completions (task_id);
+
+CREATE TABLE IF NOT EXISTS undo_log (
+	token CHAR(32) PRIMARY KEY,
+	action VARCHAR(16) NOT NULL,
+	task_id INTEGER NOT NULL,
+	snapshot TEXT NOT NULL,
+	expires_at INTEGER NOT NULL,
+	user_id INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	login VARCHAR(64) NOT NULL UNIQUE,
+	password_hash VARCHAR(60) NOT NULL,
+	created_at VARCHAR(32) NOT NULL DEFAULT ""
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	scope VARCHAR(16) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	created_at VARCHAR(32) NOT NULL,
+	last_used_at VARCHAR(32) NOT NULL DEFAULT ""
+);
+
+CREATE TABLE IF NOT EXISTS lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(128) NOT NULL,
+	created_at VARCHAR(32) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS list_members (
+	list_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	added_at VARCHAR(32) NOT NULL,
+	PRIMARY KEY (list_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS list_members_user ON list_members (user_id);
@@ -0,0 +1,3 @@
+-- Удалённые задачи попадают в корзину: deleted_at — момент удаления, пусто у остальных задач
+ALTER TABLE scheduler ADD COLUMN deleted_at VARCHAR(32) NOT NULL DEFAULT '';
+CREATE INDEX scheduler_deleted ON scheduler (deleted_at);
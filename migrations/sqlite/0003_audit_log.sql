@@ -0,0 +1,24 @@
+-- Журнал изменений задач. Записи только добавляются: триггеры не дают их менять и удалять.
+-- user_id — кто выполнил действие, list_id — список задачи (0 у личных задач), task_before
+-- и task_after — задача в JSON до и после изменения (пусто, если задачи не было).
+CREATE TABLE audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at VARCHAR(32) NOT NULL,
+	user_id INTEGER NOT NULL,
+	action VARCHAR(16) NOT NULL,
+	task_id INTEGER NOT NULL,
+	list_id INTEGER NOT NULL DEFAULT 0,
+	task_before TEXT NOT NULL DEFAULT '',
+	task_after TEXT NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT ''
+);
+CREATE INDEX audit_log_task ON audit_log (task_id, id);
+CREATE INDEX audit_log_user ON audit_log (user_id, id);
+CREATE INDEX audit_log_list ON audit_log (list_id, id);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log BEGIN
+	SELECT RAISE(ABORT, 'записи журнала аудита нельзя менять');
+END;
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log BEGIN
+	SELECT RAISE(ABORT, 'записи журнала аудита нельзя удалять');
+END;
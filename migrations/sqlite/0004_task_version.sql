@@ -0,0 +1,3 @@
+-- version растёт при каждом изменении задачи: по нему клиент узнаёт, что задачу успели изменить
+-- после того, как он её прочитал (ETag и If-Match в /api/task)
+ALTER TABLE scheduler ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
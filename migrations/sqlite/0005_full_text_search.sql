@@ -0,0 +1,20 @@
+-- Полнотекстовый индекс FTS4 по заголовкам и комментариям задач и триггеры, которые поддерживают
+-- его в актуальном состоянии. FTS4 входит в любую сборку go-sqlite3, поэтому базу с индексом
+-- можно открыть любой сборкой приложения. Триггеры прежнего индекса FTS5 удаляет шаг
+-- dropFullTextFTS5 перед этим файлом.
+CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_search USING fts4(
+	content="scheduler", title, comment, tokenize=unicode61 "remove_diacritics=2"
+);
+CREATE TRIGGER IF NOT EXISTS scheduler_search_insert AFTER INSERT ON scheduler BEGIN
+	INSERT INTO scheduler_search (docid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+CREATE TRIGGER IF NOT EXISTS scheduler_search_before_delete BEFORE DELETE ON scheduler BEGIN
+	DELETE FROM scheduler_search WHERE docid = old.id;
+END;
+CREATE TRIGGER IF NOT EXISTS scheduler_search_before_update BEFORE UPDATE OF title, comment ON scheduler BEGIN
+	DELETE FROM scheduler_search WHERE docid = old.id;
+END;
+CREATE TRIGGER IF NOT EXISTS scheduler_search_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+	INSERT INTO scheduler_search (docid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+INSERT INTO scheduler_search (scheduler_search) VALUES ('rebuild');
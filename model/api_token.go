@@ -0,0 +1,13 @@
+package model
+
+// APIToken — личный токен для скриптов и интеграций. Сам токен хранится только в виде хеша
+// и возвращается в поле Token один раз, при создании.
+type APIToken struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Scope      string `json:"scope"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+	Token      string `json:"token,omitempty"`
+	UserID     int64  `json:"-"`
+}
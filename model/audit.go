@@ -0,0 +1,17 @@
+package model
+
+import "encoding/json"
+
+// AuditEntry — запись журнала изменений задачи: кто (UserID, 0 — общий список задач), что сделал
+// и в каком запросе. Before и After — задача до и после изменения; у созданной задачи нет Before.
+type AuditEntry struct {
+	ID        string          `json:"id"`
+	CreatedAt string          `json:"created_at"`
+	UserID    string          `json:"user_id"`
+	Action    string          `json:"action"`
+	TaskID    string          `json:"task_id"`
+	ListID    int64           `json:"list_id,omitempty,string"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+}
@@ -0,0 +1,24 @@
+package model
+
+// List — общий список задач. Role — роль текущего пользователя в списке.
+type List struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Role      string `json:"role,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// ListMember — участник списка и его роль: viewer, editor или owner
+type ListMember struct {
+	UserID  string `json:"user_id"`
+	Login   string `json:"login"`
+	Role    string `json:"role"`
+	AddedAt string `json:"added_at,omitempty"`
+}
+
+// ListInvitation — приглашение пользователя в список: кого и с какой ролью добавить
+type ListInvitation struct {
+	ListID string `json:"list_id"`
+	Login  string `json:"login"`
+	Role   string `json:"role"`
+}
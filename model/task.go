@@ -6,9 +6,50 @@ type Tasks struct {
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	// EndDate и MaxCount ограничивают повторения: после последней даты или после
+	// MaxCount выполнений задача больше не переносится. DoneCount ведёт сервер.
+	EndDate   string `json:"end_date,omitempty"`
+	MaxCount  int    `json:"max_count,omitempty"`
+	DoneCount int    `json:"done_count,omitempty"`
+	// AnchorDate — исходная дата повторения, если текущее повторение перенесено на Date
+	AnchorDate string `json:"anchor_date,omitempty"`
+	// CompletedAt заполняется, когда задача выполнена окончательно и перенесена в архив
+	CompletedAt string `json:"completed_at,omitempty"`
+	// CreatedAt — момент создания задачи; у задач, созданных до появления поля, пусто
+	CreatedAt string `json:"created_at,omitempty"`
+	// DeletedAt заполняется, когда задача удалена и лежит в корзине
+	DeletedAt string `json:"deleted_at,omitempty"`
+	// Version растёт при каждом изменении задачи. Сервер возвращает его в ETag, а клиент,
+	// меняя задачу, передаёт в If-Match или в этом поле версию, которую он видел.
+	Version int64 `json:"version,omitempty,string"`
+	// UserID — владелец задачи; 0 — общий список, доступный без входа или по TODO_PASSWORD.
+	// У задачи из списка это пользователь, который её создал.
+	UserID int64 `json:"-"`
+	// ListID — список, которому принадлежит задача; 0 — личная задача пользователя UserID
+	ListID int64 `json:"list_id,omitempty,string"`
+	// Snippet — фрагмент заголовка или комментария с выделенными совпадениями, заполняется только при поиске
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// TaskCompletion — запись о выполнении задачи: дата выполненного повторения и момент отметки
+type TaskCompletion struct {
+	TaskID string `json:"task_id"`
+	Date   string `json:"date"`
+	DoneAt string `json:"done_at"`
+}
+
+// TaskException — исключение из правила повторения: пропущенное (MovedTo пусто)
+// или перенесённое на другую дату повторение задачи
+type TaskException struct {
+	TaskID  string `json:"task_id"`
+	Date    string `json:"date"`
+	MovedTo string `json:"moved_to,omitempty"`
 }
 
 type TaskResponse struct {
 	ID    string `json:"id,omitempty"`
 	Error string `json:"error,omitempty"`
+	Field string `json:"field,omitempty"`
+	// Task — текущее состояние задачи, если её успели изменить (ответ 412)
+	Task *Tasks `json:"task,omitempty"`
 }
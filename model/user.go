@@ -0,0 +1,15 @@
+package model
+
+// User — учётная запись. Хеш пароля никогда не отдаётся в JSON.
+type User struct {
+	ID           string `json:"id"`
+	Login        string `json:"login"`
+	PasswordHash string `json:"-"`
+	CreatedAt    string `json:"created_at,omitempty"`
+}
+
+// Credentials — логин и пароль из запросов регистрации и входа
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"go_final_project/model"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+
+	// APITokenPrefix помогает узнать токен планировщика, например в логах CI или при поиске утечек
+	APITokenPrefix = "todo_"
+
+	MaxAPITokenNameLength = 64
+
+	// APITokenUsageInterval — как часто обновляется время последнего использования токена:
+	// запись при каждом запросе нагружала бы базу, а точнее минуты это время не нужно
+	APITokenUsageInterval = time.Minute
+)
+
+// NewAPIToken создаёт случайный токен и возвращает его вместе с хешем для хранения в базе
+func NewAPIToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := APITokenPrefix + hex.EncodeToString(b)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken — у токена достаточно случайных байт, поэтому для хранения хватает SHA-256 без соли
+func HashAPIToken(token string) string {
+	return sha256Hex(token)
+}
+
+// ValidateAPIToken проверяет название и область действия нового токена; пустая область — полный доступ
+func ValidateAPIToken(token *model.APIToken) error {
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return &ValidationError{Field: "name", Message: "Не указано название токена"}
+	}
+	if len([]rune(token.Name)) > MaxAPITokenNameLength {
+		return &ValidationError{Field: "name", Message: "Название токена должно быть не длиннее 64 символов"}
+	}
+	if token.Scope == "" {
+		token.Scope = ScopeWrite
+	}
+	if token.Scope != ScopeRead && token.Scope != ScopeWrite {
+		return &ValidationError{Field: "scope", Message: "Область действия токена должна быть read или write"}
+	}
+	return nil
+}
+
+const apiTokenColumns = "id, user_id, name, scope, created_at, last_used_at"
+
+func scanAPIToken(row rowScanner) (model.APIToken, error) {
+	var token model.APIToken
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Scope, &token.CreatedAt, &token.LastUsedAt)
+	return token, err
+}
+
+func (r *UserRepository) CreateAPIToken(userID int64, token model.APIToken, hash string) (model.APIToken, error) {
+	token.UserID = userID
+	token.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	query := "INSERT INTO api_tokens (user_id, name, scope, token_hash, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id"
+	var id int64
+	if err := r.DB.QueryRow(query, userID, token.Name, token.Scope, hash, token.CreatedAt).Scan(&id); err != nil {
+		return model.APIToken{}, err
+	}
+	token.ID = strconv.FormatInt(id, 10)
+	return token, nil
+}
+
+func (r *UserRepository) GetAPITokens(userID int64) ([]model.APIToken, error) {
+	rows, err := r.DB.Query("SELECT "+apiTokenColumns+" FROM api_tokens WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []model.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// touchAPIToken отмечает время использования токена, если с прошлой отметки прошло
+// не меньше APITokenUsageInterval, и сообщает, изменилось ли оно
+func touchAPIToken(token *model.APIToken, now time.Time) bool {
+	lastUsed, err := time.Parse(time.RFC3339, token.LastUsedAt)
+	if err == nil && now.Sub(lastUsed) < APITokenUsageInterval {
+		return false
+	}
+	token.LastUsedAt = now.UTC().Format(time.RFC3339)
+	return true
+}
+
+// GetAPITokenByHash находит токен по хешу и отмечает время его использования. Отметка
+// не обязательна для входа: если записать её не удалось, ошибка только попадает в лог.
+func (r *UserRepository) GetAPITokenByHash(hash string, now time.Time) (model.APIToken, error) {
+	token, err := scanAPIToken(r.DB.QueryRow("SELECT "+apiTokenColumns+" FROM api_tokens WHERE token_hash = ?", hash))
+	if err != nil {
+		return model.APIToken{}, err
+	}
+	if touchAPIToken(&token, now) {
+		_, err = r.DB.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", token.LastUsedAt, token.ID)
+		if err != nil {
+			log.Printf("Не удалось отметить использование токена %s: %v", token.ID, err)
+		}
+	}
+	return token, nil
+}
+
+// DeleteAPIToken отзывает токен пользователя; чужой токен не удаляется, и возвращается sql.ErrNoRows
+func (r *UserRepository) DeleteAPIToken(userID int64, id int) error {
+	result, err := r.DB.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
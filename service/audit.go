@@ -0,0 +1,222 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"go_final_project/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Действия, которые записываются в журнал аудита
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionDone   = "done"
+	// AuditActionSkip и AuditActionMove — пропуск и перенос отдельного повторения
+	AuditActionSkip = "skip"
+	AuditActionMove = "move"
+	// AuditActionArchive — перенос в архив задачи, у которой не осталось повторений
+	AuditActionArchive = "archive"
+	// AuditActionRestore и AuditActionPurge — восстановление из корзины и окончательное удаление
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+	// AuditActionUndo — отмена выполнения или удаления по токену отмены
+	AuditActionUndo = "undo"
+
+	// RequestIDHeader — заголовок с идентификатором запроса. Если клиент его не передал,
+	// сервер придумывает свой; в обоих случаях он возвращается в ответе и попадает в журнал аудита.
+	RequestIDHeader = "X-Request-ID"
+	// MaxRequestIDLength — самый длинный идентификатор запроса, который сервер принимает от клиента
+	MaxRequestIDLength = 64
+)
+
+var auditActions = map[string]bool{
+	AuditActionCreate:  true,
+	AuditActionUpdate:  true,
+	AuditActionDelete:  true,
+	AuditActionDone:    true,
+	AuditActionSkip:    true,
+	AuditActionMove:    true,
+	AuditActionArchive: true,
+	AuditActionRestore: true,
+	AuditActionPurge:   true,
+	AuditActionUndo:    true,
+}
+
+// Actor — кто и в каком запросе меняет задачу. Методы хранилища, которые записывают
+// изменение в журнал аудита, принимают его вместо userID.
+type Actor struct {
+	UserID    int64
+	RequestID string
+}
+
+// AuditFilter — условия выборки из журнала аудита. Нулевые значения не ограничивают выборку.
+type AuditFilter struct {
+	// UserID — кто читает журнал: он видит записи о своих личных задачах и о задачах своих списков
+	UserID int64
+	TaskID int64
+	// ListID — записи о задачах списка; право их видеть проверяет вызывающий
+	ListID int64
+	// ActorID — только действия этого пользователя (0 — общий список задач)
+	ActorID *int64
+	Action  string
+	// From и To — границы дат записи в формате DateFormat по UTC включительно
+	From string
+	To   string
+	// BeforeID — продолжение выборки: записи с id меньше этого
+	BeforeID int64
+	Limit    int
+}
+
+// AuditPage — записи журнала от новых к старым. NextBefore пусто, если записей больше нет.
+type AuditPage struct {
+	Entries    []model.AuditEntry `json:"entries"`
+	NextBefore string             `json:"next_before,omitempty"`
+}
+
+// ValidAuditAction сообщает, записывается ли такое действие в журнал аудита
+func ValidAuditAction(action string) bool {
+	return auditActions[action]
+}
+
+// NewRequestID придумывает идентификатор для запроса, который пришёл без X-Request-ID
+func NewRequestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// auditDay переводит дату из DateFormat в начало даты created_at (RFC 3339 по UTC)
+func auditDay(date string, days int) string {
+	day, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return date
+	}
+	return day.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// newAuditEntry описывает изменение задачи; before или after равен nil, если задачи до или после изменения нет
+func newAuditEntry(actor Actor, action string, before *model.Tasks, after *model.Tasks) (model.AuditEntry, error) {
+	entry := model.AuditEntry{
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		UserID:    strconv.FormatInt(actor.UserID, 10),
+		Action:    action,
+		RequestID: actor.RequestID,
+	}
+	for _, side := range []struct {
+		task *model.Tasks
+		data *json.RawMessage
+	}{{before, &entry.Before}, {after, &entry.After}} {
+		if side.task == nil {
+			continue
+		}
+		data, err := json.Marshal(side.task)
+		if err != nil {
+			return model.AuditEntry{}, err
+		}
+		*side.data = data
+		entry.TaskID, entry.ListID = side.task.ID, side.task.ListID
+	}
+	return entry, nil
+}
+
+// getTaskTx читает задачу внутри транзакции без проверки прав: их уже проверил запрос, который её меняет
+func getTaskTx(tx *Tx, id any) (model.Tasks, error) {
+	return scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM scheduler WHERE id = ?", id))
+}
+
+// writeAudit записывает изменение задачи в журнал в той же транзакции, что и само изменение
+func writeAudit(tx *Tx, actor Actor, action string, before *model.Tasks, after *model.Tasks) error {
+	entry, err := newAuditEntry(actor, action, before, after)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO audit_log (created_at, user_id, action, task_id, list_id, task_before, task_after, request_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, entry.CreatedAt, actor.UserID, entry.Action, entry.TaskID, entry.ListID,
+		string(entry.Before), string(entry.After), entry.RequestID)
+	return err
+}
+
+// GetAuditLog возвращает записи журнала, которые видит filter.UserID, от новых к старым
+func (r *TaskRepository) GetAuditLog(filter AuditFilter) (AuditPage, error) {
+	conditions := []string{visibleTask}
+	args := []any{filter.UserID, filter.UserID}
+	if filter.TaskID != 0 {
+		conditions = append(conditions, "task_id = ?")
+		args = append(args, filter.TaskID)
+	}
+	if filter.ListID != 0 {
+		conditions = append(conditions, "list_id = ?")
+		args = append(args, filter.ListID)
+	}
+	if filter.ActorID != nil {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, *filter.ActorID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.From != "" {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, auditDay(filter.From, 0))
+	}
+	if filter.To != "" {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, auditDay(filter.To, 1))
+	}
+	if filter.BeforeID != 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+
+	query := `SELECT id, created_at, user_id, action, task_id, list_id, task_before, task_after, request_id
+		FROM audit_log WHERE ` + strings.Join(conditions, " AND ") + " ORDER BY id DESC LIMIT ?"
+	rows, err := r.DB.Query(query, append(args, filter.Limit+1)...)
+	if err != nil {
+		return AuditPage{}, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var entry model.AuditEntry
+		var before, after string
+		err := rows.Scan(&entry.ID, &entry.CreatedAt, &entry.UserID, &entry.Action, &entry.TaskID, &entry.ListID,
+			&before, &after, &entry.RequestID)
+		if err != nil {
+			return AuditPage{}, err
+		}
+		if before != "" {
+			entry.Before = json.RawMessage(before)
+		}
+		if after != "" {
+			entry.After = json.RawMessage(after)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return AuditPage{}, err
+	}
+	return auditPage(filter, entries), nil
+}
+
+// auditPage оставляет filter.Limit записей из выбранных с запасом в одну и по ней понимает, есть ли продолжение
+func auditPage(filter AuditFilter, entries []model.AuditEntry) AuditPage {
+	page := AuditPage{Entries: entries}
+	if len(entries) > filter.Limit {
+		page.Entries = entries[:filter.Limit]
+		page.NextBefore = page.Entries[filter.Limit-1].ID
+	}
+	if page.Entries == nil {
+		page.Entries = []model.AuditEntry{}
+	}
+	return page
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go_final_project/model"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenTTL — срок действия токена; столько же живёт cookie, которую ставит страница входа
+const TokenTTL = 8 * time.Hour
+
+var (
+	ErrWrongPassword = errors.New("неверный пароль")
+	ErrInvalidToken  = errors.New("недействительный токен")
+)
+
+// Authenticator проверяет пароль из TODO_PASSWORD и выдаёт подписанные JWT — как для общего
+// списка задач, так и для пользователей. В токен записывается хеш пароля, поэтому после смены
+// пароля старые токены перестают действовать.
+type Authenticator struct {
+	password string
+	secret   []byte
+}
+
+// tokenClaims — содержимое токена: стандартные поля, пользователь (0 — вход по TODO_PASSWORD)
+// и хеш пароля, с которым выполнен вход
+type tokenClaims struct {
+	PasswordHash string `json:"pwd"`
+	UserID       int64  `json:"uid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewAuthenticator создаёт проверку по паролю. Если secret пуст, токены подписываются самим паролем,
+// а если не задан и пароль — случайным ключом, который действует до перезапуска сервера.
+// С пустым паролем общий список задач доступен без входа.
+func NewAuthenticator(password string, secret string) (*Authenticator, error) {
+	key := []byte(secret)
+	if secret == "" {
+		key = []byte(password)
+	}
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("не удалось создать ключ подписи токенов: %w", err)
+		}
+	}
+	return &Authenticator{password: password, secret: key}, nil
+}
+
+func (a *Authenticator) Enabled() bool {
+	return a.password != ""
+}
+
+// SignIn сверяет пароль и возвращает токен
+func (a *Authenticator) SignIn(password string, now time.Time) (string, error) {
+	if !a.Enabled() || subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) != 1 {
+		return "", ErrWrongPassword
+	}
+	return a.issue(0, sha256Hex(a.password), now)
+}
+
+// UserToken выдаёт токен пользователю, пароль которого уже проверен
+func (a *Authenticator) UserToken(user model.User, now time.Time) (string, error) {
+	id, err := strconv.ParseInt(user.ID, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	return a.issue(id, sha256Hex(user.PasswordHash), now)
+}
+
+func (a *Authenticator) issue(userID int64, passwordHash string, now time.Time) (string, error) {
+	claims := tokenClaims{
+		PasswordHash: passwordHash,
+		UserID:       userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+}
+
+// Verify проверяет подпись и срок действия токена и то, что он выдан для текущего пароля,
+// и возвращает пользователя токена. Пользователь загружается через lookup.
+func (a *Authenticator) Verify(token string, lookup func(id int64) (model.User, error)) (int64, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	var expected string
+	if claims.UserID == 0 {
+		if !a.Enabled() {
+			return 0, ErrInvalidToken
+		}
+		expected = sha256Hex(a.password)
+	} else {
+		user, err := lookup(claims.UserID)
+		if err != nil {
+			return 0, ErrInvalidToken
+		}
+		expected = sha256Hex(user.PasswordHash)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.PasswordHash), []byte(expected)) != 1 {
+		return 0, ErrInvalidToken
+	}
+	return claims.UserID, nil
+}
+
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,24 @@
 package service
 
+import "time"
+
 const (
 	DateFormat     = "20060102"
 	TaskQueryLimit = 50
+	// TaskPageMaxLimit — наибольший размер страницы, который может запросить клиент
+	TaskPageMaxLimit = 200
+
+	// SearchDateFormat — формат даты в строке поиска /api/tasks
+	SearchDateFormat = "02.01.2006"
+
+	PreviewDefaultCount = 10
+	PreviewMaxCount     = 100
+
+	// DefaultUndoWindow — сколько времени после выполнения или удаления задачи действует токен отмены
+	DefaultUndoWindow = 5 * time.Minute
+
+	// DefaultTrashRetention — сколько удалённая задача хранится в корзине, прежде чем удалиться окончательно
+	DefaultTrashRetention = 30 * 24 * time.Hour
+	// TrashPurgeInterval — как часто сервер очищает корзину от задач старше срока хранения
+	TrashPurgeInterval = time.Hour
 )
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Драйверы database/sql, с которыми работают репозитории
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// driverSQLiteSearch — драйвер SQLite, в соединениях которого есть функция search_rank
+// для ранжирования полнотекстового поиска
+const driverSQLiteSearch = "sqlite3_search"
+
+func init() {
+	sql.Register(driverSQLiteSearch, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("search_rank", fullTextRank, true)
+		},
+	})
+}
+
+// Open открывает базу драйвером DriverSQLite или DriverPostgres. Для SQLite соединения получают
+// функции, которые нужны запросам репозиториев, поэтому открывать базу для них нужно здесь.
+func Open(driver string, dsn string) (*sql.DB, error) {
+	if driver == DriverSQLite {
+		return sql.Open(driverSQLiteSearch, dsn)
+	}
+	return sql.Open(driver, dsn)
+}
+
+// DB — соединение с базой SQLite или PostgreSQL. Запросы в репозиториях пишутся с плейсхолдерами ?
+// и на SQL, общем для обеих баз; для PostgreSQL DB заменяет плейсхолдеры на $1, $2, ...
+type DB struct {
+	*sql.DB
+	Driver string
+}
+
+func NewDB(db *sql.DB, driver string) *DB {
+	return &DB{DB: db, Driver: driver}
+}
+
+func (db *DB) rebind(query string) string {
+	if db.Driver == DriverPostgres {
+		return sqlx.Rebind(sqlx.DOLLAR, query)
+	}
+	return query
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.DB.Exec(db.rebind(query), args...)
+}
+
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.DB.Query(db.rebind(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.DB.QueryRow(db.rebind(query), args...)
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+// Tx — транзакция DB с той же заменой плейсхолдеров
+type Tx struct {
+	*sql.Tx
+	db *DB
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.Tx.Exec(tx.db.rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.db.rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRow(tx.db.rebind(query), args...)
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	LangRU = "ru"
+	LangEN = "en"
+)
+
+var (
+	ruMonthsGenitive = []string{"", "января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	ruMonthsPrepositional = []string{"", "январе", "феврале", "марте", "апреле", "мае", "июне",
+		"июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+	enMonths = []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+
+	// дни недели в винительном падеже и род, по которому согласуется порядковое числительное
+	ruWeekdaysAccusative = []string{"", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу", "воскресенье"}
+	ruWeekdaysGender     = []string{"", "m", "m", "f", "m", "f", "f", "n"}
+	ruWeekdaysDative     = []string{"", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам", "воскресеньям"}
+	enWeekdays           = []string{"", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+)
+
+// ruUnit — единица периода повторения: формы для 1, 2–4 и 5+ и форма слова «каждый» для единственного числа
+type ruUnit struct {
+	one, few, many, each string
+}
+
+var ruUnits = map[string]ruUnit{
+	FreqDaily:   {"день", "дня", "дней", "каждый"},
+	FreqWeekly:  {"неделю", "недели", "недель", "каждую"},
+	FreqMonthly: {"месяц", "месяца", "месяцев", "каждый"},
+	FreqYearly:  {"год", "года", "лет", "каждый"},
+}
+
+var enUnits = map[string]string{
+	FreqDaily:   "day",
+	FreqWeekly:  "week",
+	FreqMonthly: "month",
+	FreqYearly:  "year",
+}
+
+// DescribeRepeat разбирает правило повторения тем же парсером, что и NextDate,
+// и возвращает его вместе с описанием на выбранном языке
+func DescribeRepeat(repeat string, lang string) (*RepeatRule, string, error) {
+	if lang != LangRU && lang != LangEN {
+		return nil, "", fmt.Errorf("неподдерживаемый язык описания: %s", lang)
+	}
+	rule, err := ParseRepeatRule(repeat)
+	if err != nil {
+		return nil, "", err
+	}
+	if lang == LangEN {
+		return rule, rule.describeEN(), nil
+	}
+	return rule, rule.describeRU(), nil
+}
+
+func (r *RepeatRule) describeRU() string {
+	var b strings.Builder
+	days, months := sortedMonthDays(r.ByMonthDay), sortedInts(r.ByMonth)
+
+	if r.isSimpleMonthly() {
+		items := make([]string, len(days))
+		for i, day := range days {
+			items[i] = ruMonthDayNominative(day)
+		}
+		b.WriteString("каждый " + joinList(items, "и") + " день ")
+		if len(months) == 0 {
+			b.WriteString("месяца")
+		} else {
+			b.WriteString(joinList(pick(ruMonthsGenitive, months), "и"))
+		}
+		return b.String()
+	}
+
+	unit := ruUnits[r.Freq]
+	switch {
+	case r.Interval == 1:
+		b.WriteString(unit.each + " " + unit.one)
+	case ruPluralForm(r.Interval) == 0:
+		b.WriteString(fmt.Sprintf("%s %d %s", unit.each, r.Interval, unit.one))
+	default:
+		b.WriteString(fmt.Sprintf("каждые %d %s", r.Interval, ruPlural(r.Interval, unit.one, unit.few, unit.many)))
+	}
+
+	var plain, ordinal []string
+	for _, d := range sortedDays(r.ByDay) {
+		if d.Ordinal == 0 {
+			plain = append(plain, ruWeekdaysDative[d.Weekday])
+		} else {
+			ordinal = append(ordinal, ruOrdinalWeekday(d))
+		}
+	}
+	var groups []string
+	if len(plain) > 0 {
+		groups = append(groups, "по "+joinList(plain, "и"))
+	}
+	groups = append(groups, ordinal...)
+	if len(groups) > 0 {
+		b.WriteString(" " + joinList(groups, "и"))
+	}
+
+	if len(days) > 0 {
+		items := make([]string, len(days))
+		for i, day := range days {
+			items[i] = ruMonthDayGenitive(day)
+		}
+		b.WriteString(" " + joinList(items, "и") + " числа")
+	}
+	if len(months) > 0 {
+		b.WriteString(" в " + joinList(pick(ruMonthsPrepositional, months), "и"))
+	}
+	if r.Count > 0 {
+		b.WriteString(fmt.Sprintf(", всего %d %s", r.Count, ruPlural(r.Count, "раз", "раза", "раз")))
+	}
+	if !r.until.IsZero() {
+		b.WriteString(", до " + r.until.Format("02.01.2006"))
+	}
+	return b.String()
+}
+
+func (r *RepeatRule) describeEN() string {
+	var b strings.Builder
+	days, months := sortedMonthDays(r.ByMonthDay), sortedInts(r.ByMonth)
+
+	if r.isSimpleMonthly() {
+		items := make([]string, len(days))
+		for i, day := range days {
+			items[i] = enOrdinalFromEnd(day)
+		}
+		b.WriteString("every " + joinList(items, "and") + " day of ")
+		if len(months) == 0 {
+			b.WriteString("the month")
+		} else {
+			b.WriteString(joinList(pick(enMonths, months), "and"))
+		}
+		return b.String()
+	}
+
+	unit := enUnits[r.Freq]
+	if r.Interval == 1 {
+		b.WriteString("every " + unit)
+	} else {
+		b.WriteString(fmt.Sprintf("every %d %ss", r.Interval, unit))
+	}
+
+	if len(r.ByDay) > 0 {
+		var items []string
+		for _, d := range sortedDays(r.ByDay) {
+			if d.Ordinal == 0 {
+				items = append(items, enWeekdays[d.Weekday])
+			} else {
+				items = append(items, "the "+enOrdinalFromEnd(d.Ordinal)+" "+enWeekdays[d.Weekday])
+			}
+		}
+		b.WriteString(" on " + joinList(items, "and"))
+	}
+	if len(days) > 0 {
+		items := make([]string, len(days))
+		for i, day := range days {
+			items[i] = enOrdinalFromEnd(day)
+		}
+		b.WriteString(" on the " + joinList(items, "and") + " day")
+		if len(months) == 0 {
+			b.WriteString(" of the month")
+		}
+	}
+	if len(months) > 0 {
+		b.WriteString(" in " + joinList(pick(enMonths, months), "and"))
+	}
+	if r.Count > 0 {
+		if r.Count == 1 {
+			b.WriteString(", 1 time")
+		} else {
+			b.WriteString(fmt.Sprintf(", %d times", r.Count))
+		}
+	}
+	if !r.until.IsZero() {
+		b.WriteString(", until " + r.until.Format("2006-01-02"))
+	}
+	return b.String()
+}
+
+// isSimpleMonthly — ежемесячное правило только по дням месяца, как "m 1 1,2";
+// такие правила описываются короче: «каждый 1-й день января и февраля»
+func (r *RepeatRule) isSimpleMonthly() bool {
+	return r.Freq == FreqMonthly && r.Interval == 1 && len(r.ByMonthDay) > 0 &&
+		len(r.ByDay) == 0 && r.Count == 0 && r.until.IsZero()
+}
+
+func ruMonthDayNominative(day int) string {
+	switch {
+	case day == -1:
+		return "последний"
+	case day == -2:
+		return "предпоследний"
+	case day < 0:
+		return strconv.Itoa(-day) + "-й с конца"
+	}
+	return strconv.Itoa(day) + "-й"
+}
+
+func ruMonthDayGenitive(day int) string {
+	switch {
+	case day == -1:
+		return "последнего"
+	case day == -2:
+		return "предпоследнего"
+	case day < 0:
+		return strconv.Itoa(-day) + "-го с конца"
+	}
+	return strconv.Itoa(day) + "-го"
+}
+
+// ruOrdinalWeekday согласует порядковое числительное с днём недели: «во 2-й вторник», «в последнюю пятницу»
+func ruOrdinalWeekday(d RepeatDay) string {
+	endings := map[string][3]string{
+		"m": {"-й", "последний", "предпоследний"},
+		"f": {"-ю", "последнюю", "предпоследнюю"},
+		"n": {"-е", "последнее", "предпоследнее"},
+	}[ruWeekdaysGender[d.Weekday]]
+
+	var ordinal string
+	switch {
+	case d.Ordinal == -1:
+		ordinal = endings[1]
+	case d.Ordinal == -2:
+		ordinal = endings[2]
+	case d.Ordinal < 0:
+		ordinal = strconv.Itoa(-d.Ordinal) + endings[0] + " с конца"
+	default:
+		ordinal = strconv.Itoa(d.Ordinal) + endings[0]
+	}
+
+	preposition := "в"
+	if d.Ordinal == 2 {
+		preposition = "во"
+	}
+	return preposition + " " + ordinal + " " + ruWeekdaysAccusative[d.Weekday]
+}
+
+// enOrdinalFromEnd возвращает "1st", "22nd", а для отрицательных номеров — "last", "second-to-last", "3rd-to-last"
+func enOrdinalFromEnd(n int) string {
+	switch {
+	case n == -1:
+		return "last"
+	case n == -2:
+		return "second-to-last"
+	case n < 0:
+		return enOrdinal(-n) + "-to-last"
+	}
+	return enOrdinal(n)
+}
+
+func enOrdinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// ruPluralForm возвращает 0 для чисел вида 1, 21, 31, 1 — для 2–4, 22–24 и 2 для остальных
+func ruPluralForm(n int) int {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	}
+	return 2
+}
+
+func ruPlural(n int, one, few, many string) string {
+	return [3]string{one, few, many}[ruPluralForm(n)]
+}
+
+// joinList соединяет элементы через запятую, а последний — через союз: «a, b и c», "a, b and c"
+func joinList(items []string, conjunction string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+}
+
+func pick(names []string, indexes []int) []string {
+	result := make([]string, len(indexes))
+	for i, index := range indexes {
+		result[i] = names[index]
+	}
+	return result
+}
+
+func sortedInts(values []int) []int {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+// sortedMonthDays ставит дни с конца месяца после обычных: 1, 15, предпоследний, последний
+func sortedMonthDays(days []int) []int {
+	key := func(day int) int {
+		if day < 0 {
+			return 100 + day
+		}
+		return day
+	}
+	sorted := append([]int(nil), days...)
+	sort.Slice(sorted, func(i, j int) bool { return key(sorted[i]) < key(sorted[j]) })
+	return sorted
+}
+
+func sortedDays(days []RepeatDay) []RepeatDay {
+	sorted := append([]RepeatDay(nil), days...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Weekday != sorted[j].Weekday {
+			return sorted[i].Weekday < sorted[j].Weekday
+		}
+		return sorted[i].Ordinal < sorted[j].Ordinal
+	})
+	return sorted
+}
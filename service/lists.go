@@ -0,0 +1,212 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"go_final_project/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Роли участников списка. Каждая следующая роль включает права предыдущей:
+// viewer видит задачи списка, editor создаёт, меняет, выполняет и удаляет их,
+// owner ещё и управляет участниками.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+const MaxListNameLength = 128
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// ErrLastOwner возвращается, если после изменения в списке не останется ни одного владельца
+var ErrLastOwner = errors.New("в списке должен остаться хотя бы один владелец")
+
+// RoleAllows сообщает, достаточно ли роли role для действия, которому нужна роль required
+func RoleAllows(role string, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
+
+// ValidateList проверяет название нового списка
+func ValidateList(list *model.List) error {
+	list.Name = strings.TrimSpace(list.Name)
+	if list.Name == "" {
+		return &ValidationError{Field: "name", Message: "Не указано название списка"}
+	}
+	if len([]rune(list.Name)) > MaxListNameLength {
+		return &ValidationError{Field: "name", Message: "Название списка должно быть не длиннее 128 символов"}
+	}
+	return nil
+}
+
+// ValidateInvitation проверяет приглашение в список; роль по умолчанию — viewer
+func ValidateInvitation(invitation *model.ListInvitation) error {
+	invitation.Login = strings.ToLower(strings.TrimSpace(invitation.Login))
+	if invitation.Login == "" {
+		return &ValidationError{Field: "login", Message: "Не указан логин пользователя"}
+	}
+	if invitation.Role == "" {
+		invitation.Role = RoleViewer
+	}
+	if _, ok := roleRanks[invitation.Role]; !ok {
+		return &ValidationError{Field: "role", Message: "Роль должна быть viewer, editor или owner"}
+	}
+	return nil
+}
+
+type ListRepository struct {
+	DB *DB
+}
+
+func NewListRepository(db *DB) *ListRepository {
+	return &ListRepository{DB: db}
+}
+
+// CreateList создаёт список, владельцем которого становится пользователь userID
+func (r *ListRepository) CreateList(userID int64, list model.List) (model.List, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return model.List{}, err
+	}
+	defer tx.Rollback()
+
+	list.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	var id int64
+	err = tx.QueryRow("INSERT INTO lists (name, created_at) VALUES (?, ?) RETURNING id", list.Name, list.CreatedAt).Scan(&id)
+	if err != nil {
+		return model.List{}, err
+	}
+	query := "INSERT INTO list_members (list_id, user_id, role, added_at) VALUES (?, ?, ?, ?)"
+	if _, err := tx.Exec(query, id, userID, RoleOwner, list.CreatedAt); err != nil {
+		return model.List{}, err
+	}
+
+	list.ID = strconv.FormatInt(id, 10)
+	list.Role = RoleOwner
+	return list, tx.Commit()
+}
+
+// GetLists возвращает списки, в которых состоит пользователь, вместе с его ролью
+func (r *ListRepository) GetLists(userID int64) ([]model.List, error) {
+	query := `SELECT l.id, l.name, m.role, l.created_at FROM lists l
+		JOIN list_members m ON m.list_id = l.id WHERE m.user_id = ? ORDER BY l.id`
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []model.List
+	for rows.Next() {
+		var list model.List
+		if err := rows.Scan(&list.ID, &list.Name, &list.Role, &list.CreatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+// GetRole возвращает роль пользователя в списке или sql.ErrNoRows, если он там не состоит
+func (r *ListRepository) GetRole(userID int64, listID int64) (string, error) {
+	var role string
+	err := r.DB.QueryRow("SELECT role FROM list_members WHERE list_id = ? AND user_id = ?", listID, userID).Scan(&role)
+	return role, err
+}
+
+func (r *ListRepository) GetMembers(listID int64) ([]model.ListMember, error) {
+	query := `SELECT m.user_id, u.login, m.role, m.added_at FROM list_members m
+		JOIN users u ON u.id = m.user_id WHERE m.list_id = ? ORDER BY m.added_at, m.user_id`
+	rows, err := r.DB.Query(query, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []model.ListMember
+	for rows.Next() {
+		var member model.ListMember
+		if err := rows.Scan(&member.UserID, &member.Login, &member.Role, &member.AddedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// SetMember добавляет пользователя в список или меняет роль участника. Понизить последнего
+// владельца нельзя — возвращается ErrLastOwner.
+func (r *ListRepository) SetMember(listID int64, userID int64, role string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if role != RoleOwner {
+		if err := checkOtherOwners(tx, listID, userID); err != nil {
+			return err
+		}
+	}
+	query := `INSERT INTO list_members (list_id, user_id, role, added_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (list_id, user_id) DO UPDATE SET role = excluded.role`
+	if _, err := tx.Exec(query, listID, userID, role, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveMember исключает пользователя из списка. Если он там не состоит, возвращается
+// sql.ErrNoRows, если это последний владелец — ErrLastOwner. Задачи, которые он создал
+// в списке, остаются в списке.
+func (r *ListRepository) RemoveMember(listID int64, userID int64) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkOtherOwners(tx, listID, userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM list_members WHERE list_id = ? AND user_id = ?", listID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// checkOtherOwners возвращает ErrLastOwner, если userID — единственный владелец списка
+func checkOtherOwners(tx *Tx, listID int64, userID int64) error {
+	var role string
+	err := tx.QueryRow("SELECT role FROM list_members WHERE list_id = ? AND user_id = ?", listID, userID).Scan(&role)
+	if err == sql.ErrNoRows || (err == nil && role != RoleOwner) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var owners int
+	query := "SELECT COUNT(*) FROM list_members WHERE list_id = ? AND role = ?"
+	if err := tx.QueryRow(query, listID, RoleOwner).Scan(&owners); err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,751 @@
+package service
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"go_final_project/model"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит задачи, пользователей и списки в памяти процесса и реализует TaskStore,
+// UserStore и ListStore. Он нужен тестам обработчиков, которым не нужна база: правила доступа
+// и постраничный вывод те же, что у SQLite, а поиск работает без индекса, как в PostgreSQL.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	tasks       map[int64]model.Tasks
+	exceptions  map[int64][]model.TaskException
+	completions map[int64][]model.TaskCompletion
+	undo        map[string]memoryUndo
+	audit       []model.AuditEntry
+
+	users   map[int64]model.User
+	tokens  map[int64]memoryToken
+	lists   map[int64]model.List
+	members map[int64]map[int64]model.ListMember
+
+	lastTaskID, lastUserID, lastTokenID, lastListID int64
+}
+
+type memoryUndo struct {
+	action    string
+	userID    int64
+	snapshot  UndoSnapshot
+	expiresAt time.Time
+}
+
+type memoryToken struct {
+	token model.APIToken
+	hash  string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tasks:       make(map[int64]model.Tasks),
+		exceptions:  make(map[int64][]model.TaskException),
+		completions: make(map[int64][]model.TaskCompletion),
+		undo:        make(map[string]memoryUndo),
+		users:       make(map[int64]model.User),
+		tokens:      make(map[int64]memoryToken),
+		lists:       make(map[int64]model.List),
+		members:     make(map[int64]map[int64]model.ListMember),
+	}
+}
+
+func parseID(id string) int64 {
+	n, _ := strconv.ParseInt(id, 10, 64)
+	return n
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func (s *MemoryStore) role(userID int64, listID int64) string {
+	return s.members[listID][userID].Role
+}
+
+// visible и editable повторяют условия visibleTask и editableTask из TaskRepository
+func (s *MemoryStore) visible(userID int64, task model.Tasks) bool {
+	if task.ListID == 0 {
+		return task.UserID == userID
+	}
+	return s.role(userID, task.ListID) != ""
+}
+
+func (s *MemoryStore) editable(userID int64, task model.Tasks) bool {
+	if task.ListID == 0 {
+		return task.UserID == userID
+	}
+	return RoleAllows(s.role(userID, task.ListID), RoleEditor)
+}
+
+// writeAudit добавляет запись в журнал аудита, как writeAudit у TaskRepository
+func (s *MemoryStore) writeAudit(actor Actor, action string, before *model.Tasks, after *model.Tasks) error {
+	entry, err := newAuditEntry(actor, action, before, after)
+	if err != nil {
+		return err
+	}
+	entry.ID = strconv.Itoa(len(s.audit) + 1)
+	s.audit = append(s.audit, entry)
+	return nil
+}
+
+func (s *MemoryStore) CreateTask(actor Actor, task model.Tasks) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastTaskID++
+	created := model.Tasks{
+		ID:        strconv.FormatInt(s.lastTaskID, 10),
+		Date:      task.Date,
+		Title:     task.Title,
+		Comment:   task.Comment,
+		Repeat:    task.Repeat,
+		EndDate:   task.EndDate,
+		MaxCount:  task.MaxCount,
+		CreatedAt: timestamp(),
+		UserID:    actor.UserID,
+		ListID:    task.ListID,
+		Version:   1,
+	}
+	s.tasks[s.lastTaskID] = created
+	return s.lastTaskID, s.writeAudit(actor, AuditActionCreate, nil, &created)
+}
+
+func (s *MemoryStore) GetTaskByID(userID int64, id int) (model.Tasks, error) {
+	task, err := s.GetTaskWithArchived(userID, id)
+	if err == nil && task.CompletedAt != "" {
+		return model.Tasks{}, sql.ErrNoRows
+	}
+	return task, err
+}
+
+func (s *MemoryStore) GetTaskWithArchived(userID int64, id int) (model.Tasks, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[int64(id)]
+	if !ok || !s.visible(userID, task) || task.DeletedAt != "" {
+		return model.Tasks{}, sql.ErrNoRows
+	}
+	return task, nil
+}
+
+func (s *MemoryStore) UpdateTask(actor Actor, task model.Tasks) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := parseID(task.ID)
+	stored, ok := s.tasks[id]
+	if !ok || !s.editable(actor.UserID, stored) || stored.CompletedAt != "" || stored.DeletedAt != "" || stored.Version != task.Version {
+		return 0, nil
+	}
+	before := stored
+	if stored.Date != task.Date {
+		stored.AnchorDate = ""
+	}
+	stored.Date, stored.Title, stored.Comment, stored.Repeat = task.Date, task.Title, task.Comment, task.Repeat
+	stored.EndDate, stored.MaxCount = task.EndDate, task.MaxCount
+	stored.Version++
+	s.tasks[id] = stored
+	return 1, s.writeAudit(actor, AuditActionUpdate, &before, &stored)
+}
+
+func (s *MemoryStore) RescheduleTask(actor Actor, task model.Tasks, exception model.TaskException) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := s.tasks[parseID(task.ID)]
+	if s.updateTaskSchedule(task) == 0 {
+		return ErrVersionConflict
+	}
+	s.saveTaskException(exception)
+	after := s.tasks[parseID(task.ID)]
+	return s.writeAudit(actor, rescheduleAction(exception), &before, &after)
+}
+
+func (s *MemoryStore) updateTaskSchedule(task model.Tasks) int64 {
+	id := parseID(task.ID)
+	stored, ok := s.tasks[id]
+	if !ok || stored.UserID != task.UserID || stored.Version != task.Version {
+		return 0
+	}
+	stored.Date, stored.Repeat, stored.DoneCount, stored.AnchorDate = task.Date, task.Repeat, task.DoneCount, task.AnchorDate
+	stored.Version++
+	s.tasks[id] = stored
+	return 1
+}
+
+func (s *MemoryStore) CompleteTask(actor Actor, task model.Tasks, completion model.TaskCompletion, finished bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, ok := s.tasks[parseID(task.ID)]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if before.UserID != task.UserID || before.Version != task.Version {
+		return ErrVersionConflict
+	}
+	id := parseID(completion.TaskID)
+	s.completions[id] = append(s.completions[id], completion)
+	if !finished {
+		s.updateTaskSchedule(task)
+	} else {
+		stored := before
+		stored.DoneCount, stored.CompletedAt = task.DoneCount, completion.DoneAt
+		stored.Version++
+		s.tasks[parseID(task.ID)] = stored
+	}
+	after := s.tasks[parseID(task.ID)]
+	return s.writeAudit(actor, AuditActionDone, &before, &after)
+}
+
+func (s *MemoryStore) ArchiveTask(actor Actor, id int, archivedAt string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[int64(id)]
+	if !ok || !s.editable(actor.UserID, task) || task.DeletedAt != "" {
+		return 0, nil
+	}
+	before := task
+	task.CompletedAt = archivedAt
+	task.Version++
+	s.tasks[int64(id)] = task
+	return 1, s.writeAudit(actor, AuditActionArchive, &before, &task)
+}
+
+func (s *MemoryStore) DeleteTask(actor Actor, id int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[int64(id)]
+	if !ok || !s.editable(actor.UserID, task) || task.DeletedAt != "" {
+		return 0, nil
+	}
+	before := task
+	task.DeletedAt = timestamp()
+	task.Version++
+	s.tasks[int64(id)] = task
+	return 1, s.writeAudit(actor, AuditActionDelete, &before, &task)
+}
+
+// GetAuditLog выбирает записи журнала по тем же условиям, что и TaskRepository.GetAuditLog
+func (s *MemoryStore) GetAuditLog(filter AuditFilter) (AuditPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []model.AuditEntry
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		entry := s.audit[i]
+		actorID := parseID(entry.UserID)
+		visible := entry.ListID == 0 && actorID == filter.UserID ||
+			entry.ListID != 0 && s.role(filter.UserID, entry.ListID) != ""
+		switch {
+		case !visible,
+			filter.TaskID != 0 && parseID(entry.TaskID) != filter.TaskID,
+			filter.ListID != 0 && entry.ListID != filter.ListID,
+			filter.ActorID != nil && actorID != *filter.ActorID,
+			filter.Action != "" && entry.Action != filter.Action,
+			filter.From != "" && entry.CreatedAt < auditDay(filter.From, 0),
+			filter.To != "" && entry.CreatedAt >= auditDay(filter.To, 1),
+			filter.BeforeID != 0 && parseID(entry.ID) >= filter.BeforeID:
+			continue
+		}
+		if entries = append(entries, entry); len(entries) > filter.Limit {
+			break
+		}
+	}
+	return auditPage(filter, entries), nil
+}
+
+func (s *MemoryStore) GetTrash(userID int64) ([]model.Tasks, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tasks []model.Tasks
+	for _, task := range s.tasks {
+		if task.DeletedAt != "" && s.visible(userID, task) {
+			tasks = append(tasks, task)
+		}
+	}
+	slices.SortFunc(tasks, func(a, b model.Tasks) int {
+		if c := strings.Compare(b.DeletedAt, a.DeletedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(parseID(b.ID), parseID(a.ID))
+	})
+	return tasks, nil
+}
+
+func (s *MemoryStore) GetTrashedTask(userID int64, id int) (model.Tasks, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[int64(id)]
+	if !ok || task.DeletedAt == "" || !s.visible(userID, task) {
+		return model.Tasks{}, sql.ErrNoRows
+	}
+	return task, nil
+}
+
+func (s *MemoryStore) RestoreTask(actor Actor, id int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[int64(id)]
+	if !ok || task.DeletedAt == "" || !s.editable(actor.UserID, task) {
+		return 0, nil
+	}
+	before := task
+	task.DeletedAt = ""
+	task.Version++
+	s.tasks[int64(id)] = task
+	return 1, s.writeAudit(actor, AuditActionRestore, &before, &task)
+}
+
+func (s *MemoryStore) PurgeTask(actor Actor, id int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[int64(id)]
+	if !ok || task.DeletedAt == "" || !s.editable(actor.UserID, task) {
+		return 0, nil
+	}
+	s.purge(int64(id))
+	return 1, s.writeAudit(actor, AuditActionPurge, &task, nil)
+}
+
+func (s *MemoryStore) PurgeTrash(deletedBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := deletedBefore.UTC().Format(time.RFC3339)
+	var purged int64
+	for id, task := range s.tasks {
+		if task.DeletedAt != "" && task.DeletedAt < before {
+			actor := Actor{UserID: task.UserID, RequestID: TrashRetentionRequestID}
+			if err := s.writeAudit(actor, AuditActionPurge, &task, nil); err != nil {
+				return purged, err
+			}
+			s.purge(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *MemoryStore) purge(id int64) {
+	delete(s.tasks, id)
+	delete(s.exceptions, id)
+	delete(s.completions, id)
+}
+
+func (s *MemoryStore) GetTaskCompletions(userID int64, taskID int) ([]model.TaskCompletion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task, ok := s.tasks[int64(taskID)]; !ok || !s.visible(userID, task) {
+		return nil, nil
+	}
+	return slices.Clone(s.completions[int64(taskID)]), nil
+}
+
+func (s *MemoryStore) saveTaskException(exception model.TaskException) {
+	id := parseID(exception.TaskID)
+	exceptions := s.exceptions[id]
+	for i, e := range exceptions {
+		if e.Date == exception.Date {
+			exceptions[i].MovedTo = exception.MovedTo
+			return
+		}
+	}
+	s.exceptions[id] = append(exceptions, exception)
+}
+
+func (s *MemoryStore) GetTaskExceptions(userID int64, taskID int) ([]model.TaskException, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task, ok := s.tasks[int64(taskID)]; !ok || !s.visible(userID, task) {
+		return nil, nil
+	}
+	exceptions := slices.Clone(s.exceptions[int64(taskID)])
+	slices.SortFunc(exceptions, func(a, b model.TaskException) int { return strings.Compare(a.Date, b.Date) })
+	return exceptions, nil
+}
+
+// ListTasks выбирает задачи по тем же условиям, что и TaskRepository.ListTasks. Текстовый поиск
+// работает как без индекса FTS4: все слова должны встретиться, задачи упорядочены по дате.
+func (s *MemoryStore) ListTasks(opts TaskListOptions) (TaskPage, error) {
+	var cursor *taskCursor
+	if opts.Cursor != "" {
+		var err error
+		if cursor, err = decodeTaskCursor(opts.Cursor, opts); err != nil {
+			return TaskPage{}, err
+		}
+	}
+
+	var date string
+	var terms []searchTerm
+	if opts.Search != "" {
+		if d, err := time.Parse(SearchDateFormat, opts.Search); err == nil {
+			date = d.Format(DateFormat)
+		} else {
+			terms = parseSearchQuery(opts.Search)
+		}
+	}
+
+	s.mu.Lock()
+	var tasks []model.Tasks
+	for _, task := range s.tasks {
+		if matchesListOptions(task, opts) && (date == "" || task.Date == date) && matchesTerms(task, terms) {
+			tasks = append(tasks, task)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(terms) > 0 {
+		slices.SortFunc(tasks, func(a, b model.Tasks) int { return compareTasks(a, b, SortByDate) })
+		offset := 0
+		if cursor != nil {
+			offset = cursor.Offset
+		}
+		tasks = tasks[min(offset, len(tasks)):]
+		return offsetPage(opts, offset, tasks[:min(opts.Limit+1, len(tasks))]), nil
+	}
+
+	// как и в listTasks, к предыдущей странице идём в обратном порядке
+	desc := opts.Desc != (cursor != nil && cursor.Before)
+	if cursor != nil {
+		from := model.Tasks{ID: strconv.FormatInt(cursor.ID, 10), Date: cursor.Key, Title: cursor.Key, CreatedAt: cursor.Key}
+		tasks = slices.DeleteFunc(tasks, func(task model.Tasks) bool {
+			c := compareTasks(task, from, opts.Sort)
+			return c == 0 || (c < 0) != desc
+		})
+	}
+	slices.SortFunc(tasks, func(a, b model.Tasks) int {
+		if desc {
+			return compareTasks(b, a, opts.Sort)
+		}
+		return compareTasks(a, b, opts.Sort)
+	})
+	return keysetPage(opts, cursor, tasks[:min(opts.Limit+1, len(tasks))]), nil
+}
+
+// matchesListOptions повторяет условия activeTask и TaskListOptions.filter
+func matchesListOptions(task model.Tasks, opts TaskListOptions) bool {
+	if task.CompletedAt != "" || task.DeletedAt != "" {
+		return false
+	}
+	if opts.ListID != 0 {
+		if task.ListID != opts.ListID {
+			return false
+		}
+	} else if task.ListID != 0 || task.UserID != opts.UserID {
+		return false
+	}
+	if (opts.From != "" && task.Date < opts.From) || (opts.To != "" && task.Date > opts.To) {
+		return false
+	}
+	if opts.Overdue && task.Date >= opts.Today {
+		return false
+	}
+	if opts.Repeating != nil && *opts.Repeating != (task.Repeat != "") {
+		return false
+	}
+	return true
+}
+
+// compareTasks сравнивает задачи по столбцу сортировки, а при равенстве — по id
+func compareTasks(a, b model.Tasks, sort string) int {
+	var c int
+	switch sort {
+	case SortByDate:
+		c = strings.Compare(a.Date, b.Date)
+	case SortByTitle:
+		c = strings.Compare(a.Title, b.Title)
+	case SortByCreated:
+		c = strings.Compare(a.CreatedAt, b.CreatedAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return cmp.Compare(parseID(a.ID), parseID(b.ID))
+}
+
+func (s *MemoryStore) SaveUndo(userID int64, token string, action string, snapshot UndoSnapshot, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for t, u := range s.undo {
+		if u.expiresAt.Before(time.Now()) {
+			delete(s.undo, t)
+		}
+	}
+	s.undo[token] = memoryUndo{action: action, userID: userID, snapshot: snapshot, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *MemoryStore) Undo(actor Actor, token string, now time.Time) (model.Tasks, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.undo[token]
+	if !ok || u.userID != actor.UserID || u.expiresAt.Unix() < now.Unix() {
+		return model.Tasks{}, sql.ErrNoRows
+	}
+
+	task := u.snapshot.Task
+	id := parseID(task.ID)
+	before, ok := s.tasks[id]
+	if !ok || !s.editable(actor.UserID, before) {
+		return model.Tasks{}, sql.ErrNoRows
+	}
+	if before.Version != u.snapshot.Version {
+		return before, ErrVersionConflict
+	}
+	stored := before
+	switch u.action {
+	case UndoActionDone:
+		stored.Date, stored.Repeat, stored.DoneCount = task.Date, task.Repeat, task.DoneCount
+		stored.AnchorDate, stored.CompletedAt = task.AnchorDate, task.CompletedAt
+		stored.Version++
+		s.tasks[id] = stored
+		if c := u.snapshot.Completion; c != nil {
+			s.completions[id] = slices.DeleteFunc(s.completions[id], func(completion model.TaskCompletion) bool {
+				return completion == *c
+			})
+		}
+	case UndoActionDelete:
+		if stored.DeletedAt == "" {
+			return before, ErrVersionConflict
+		}
+		stored.DeletedAt = ""
+		stored.Version++
+		s.tasks[id] = stored
+	default:
+		return model.Tasks{}, errors.New("неизвестное действие для отмены: " + u.action)
+	}
+
+	after := s.tasks[id]
+	if err := s.writeAudit(actor, AuditActionUndo, &before, &after); err != nil {
+		return model.Tasks{}, err
+	}
+	delete(s.undo, token)
+	return task, nil
+}
+
+func (s *MemoryStore) CreateUser(login string, passwordHash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Login == login {
+			return 0, ErrLoginTaken
+		}
+	}
+	s.lastUserID++
+	s.users[s.lastUserID] = model.User{
+		ID:           strconv.FormatInt(s.lastUserID, 10),
+		Login:        login,
+		PasswordHash: passwordHash,
+		CreatedAt:    timestamp(),
+	}
+	return s.lastUserID, nil
+}
+
+func (s *MemoryStore) GetUserByLogin(login string) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Login == login {
+			return user, nil
+		}
+	}
+	return model.User{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) GetUserByID(id int64) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return model.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) CreateAPIToken(userID int64, token model.APIToken, hash string) (model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastTokenID++
+	token.ID = strconv.FormatInt(s.lastTokenID, 10)
+	token.UserID = userID
+	token.CreatedAt = timestamp()
+	token.LastUsedAt = ""
+	token.Token = ""
+	s.tokens[s.lastTokenID] = memoryToken{token: token, hash: hash}
+	return token, nil
+}
+
+func (s *MemoryStore) GetAPITokens(userID int64) ([]model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []model.APIToken
+	for _, t := range s.tokens {
+		if t.token.UserID == userID {
+			tokens = append(tokens, t.token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b model.APIToken) int { return cmp.Compare(parseID(a.ID), parseID(b.ID)) })
+	return tokens, nil
+}
+
+func (s *MemoryStore) GetAPITokenByHash(hash string, now time.Time) (model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.tokens {
+		if t.hash == hash {
+			touchAPIToken(&t.token, now)
+			s.tokens[id] = t
+			return t.token, nil
+		}
+	}
+	return model.APIToken{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) DeleteAPIToken(userID int64, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.tokens[int64(id)]; !ok || t.token.UserID != userID {
+		return sql.ErrNoRows
+	}
+	delete(s.tokens, int64(id))
+	return nil
+}
+
+func (s *MemoryStore) CreateList(userID int64, list model.List) (model.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastListID++
+	list.ID = strconv.FormatInt(s.lastListID, 10)
+	list.CreatedAt = timestamp()
+	list.Role = ""
+	s.lists[s.lastListID] = list
+	s.members[s.lastListID] = map[int64]model.ListMember{
+		userID: {UserID: strconv.FormatInt(userID, 10), Role: RoleOwner, AddedAt: list.CreatedAt},
+	}
+
+	list.Role = RoleOwner
+	return list, nil
+}
+
+func (s *MemoryStore) GetLists(userID int64) ([]model.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lists []model.List
+	for id, list := range s.lists {
+		if role := s.role(userID, id); role != "" {
+			list.Role = role
+			lists = append(lists, list)
+		}
+	}
+	slices.SortFunc(lists, func(a, b model.List) int { return cmp.Compare(parseID(a.ID), parseID(b.ID)) })
+	return lists, nil
+}
+
+func (s *MemoryStore) GetRole(userID int64, listID int64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role := s.role(userID, listID)
+	if role == "" {
+		return "", sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func (s *MemoryStore) GetMembers(listID int64) ([]model.ListMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var members []model.ListMember
+	for userID, member := range s.members[listID] {
+		user, ok := s.users[userID]
+		if !ok {
+			continue
+		}
+		member.Login = user.Login
+		members = append(members, member)
+	}
+	slices.SortFunc(members, func(a, b model.ListMember) int {
+		if c := strings.Compare(a.AddedAt, b.AddedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(parseID(a.UserID), parseID(b.UserID))
+	})
+	return members, nil
+}
+
+func (s *MemoryStore) SetMember(listID int64, userID int64, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role != RoleOwner && s.lastOwner(listID, userID) {
+		return ErrLastOwner
+	}
+	if s.members[listID] == nil {
+		s.members[listID] = make(map[int64]model.ListMember)
+	}
+	member, ok := s.members[listID][userID]
+	if !ok {
+		member = model.ListMember{UserID: strconv.FormatInt(userID, 10), AddedAt: timestamp()}
+	}
+	member.Role = role
+	s.members[listID][userID] = member
+	return nil
+}
+
+func (s *MemoryStore) RemoveMember(listID int64, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.members[listID][userID]; !ok {
+		return sql.ErrNoRows
+	}
+	if s.lastOwner(listID, userID) {
+		return ErrLastOwner
+	}
+	delete(s.members[listID], userID)
+	return nil
+}
+
+// lastOwner сообщает, что userID — единственный владелец списка, как checkOtherOwners
+func (s *MemoryStore) lastOwner(listID int64, userID int64) bool {
+	if s.role(userID, listID) != RoleOwner {
+		return false
+	}
+	owners := 0
+	for _, member := range s.members[listID] {
+		if member.Role == RoleOwner {
+			owners++
+		}
+	}
+	return owners <= 1
+}
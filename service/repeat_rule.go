@@ -0,0 +1,231 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RepeatFormatLegacy = "legacy"
+	RepeatFormatRRule  = "rrule"
+
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// RepeatDay — день недели правила (1 — понедельник, 7 — воскресенье) и необязательный
+// порядковый номер внутри месяца или года (2 — второй, -1 — последний)
+type RepeatDay struct {
+	Weekday int `json:"weekday"`
+	Ordinal int `json:"ordinal,omitempty"`
+}
+
+// RepeatRule — разобранное правило повторения. Правила "y", "d", "w" и "m" и правила RRULE
+// приводятся к одной структуре, поэтому и расчёт дат, и их описание работают с одними данными.
+type RepeatRule struct {
+	Format     string      `json:"format"`
+	Freq       string      `json:"freq"`
+	Interval   int         `json:"interval"`
+	ByDay      []RepeatDay `json:"by_day,omitempty"`
+	ByMonthDay []int       `json:"by_month_day,omitempty"`
+	ByMonth    []int       `json:"by_month,omitempty"`
+	Count      int         `json:"count,omitempty"`
+	Until      string      `json:"until,omitempty"`
+
+	until time.Time
+}
+
+// ParseRepeatRule разбирает строку правила повторения в том виде, в котором она хранится в задаче
+func ParseRepeatRule(repeat string) (*RepeatRule, error) {
+	if repeat == "" {
+		return nil, fmt.Errorf("правило повторения не указано")
+	}
+	if strings.HasPrefix(repeat, RRulePrefix) {
+		return parseRRule(repeat)
+	}
+
+	rule := &RepeatRule{Format: RepeatFormatLegacy, Interval: 1}
+	switch {
+	case repeat == "y":
+		rule.Freq = FreqYearly
+	case strings.HasPrefix(repeat, "d "):
+		days, err := strconv.Atoi(strings.TrimPrefix(repeat, "d "))
+		if err != nil || days < 1 || days > 400 {
+			return nil, fmt.Errorf("наверное количество дней: %v", days)
+		}
+		rule.Freq = FreqDaily
+		rule.Interval = days
+	case strings.HasPrefix(repeat, "w "):
+		weekdays, err := parseWeekdays(strings.TrimPrefix(repeat, "w "))
+		if err != nil {
+			return nil, err
+		}
+		rule.Freq = FreqWeekly
+		rule.ByDay = weekdays
+	case strings.HasPrefix(repeat, "m "):
+		days, months, err := parseMonthRule(strings.TrimPrefix(repeat, "m "))
+		if err != nil {
+			return nil, err
+		}
+		rule.Freq = FreqMonthly
+		rule.ByMonthDay = days
+		rule.ByMonth = months
+	default:
+		return nil, fmt.Errorf("неподдерживаемое правило повторения: %s", repeat)
+	}
+	return rule, nil
+}
+
+// String возвращает каноническую запись правила: списки отсортированы и без повторов,
+// у RRULE параметры идут в фиксированном порядке, а INTERVAL=1 опускается
+func (r *RepeatRule) String() string {
+	if r.Format == RepeatFormatRRule {
+		return r.rruleString()
+	}
+
+	switch r.Freq {
+	case FreqYearly:
+		return "y"
+	case FreqDaily:
+		return "d " + strconv.Itoa(r.Interval)
+	case FreqWeekly:
+		days := make([]int, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.Weekday
+		}
+		return "w " + joinInts(uniqueInts(sortedInts(days)))
+	}
+
+	result := "m " + joinInts(uniqueInts(sortedMonthDays(r.ByMonthDay)))
+	if len(r.ByMonth) > 0 {
+		result += " " + joinInts(uniqueInts(sortedInts(r.ByMonth)))
+	}
+	return result
+}
+
+func (r *RepeatRule) rruleString() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval != 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, d := range sortedDays(r.ByDay) {
+			day := rruleWeekdayCodes[d.Weekday]
+			if d.Ordinal != 0 {
+				day = strconv.Itoa(d.Ordinal) + day
+			}
+			if len(days) == 0 || days[len(days)-1] != day {
+				days = append(days, day)
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(uniqueInts(sortedMonthDays(r.ByMonthDay))))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(uniqueInts(sortedInts(r.ByMonth))))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != "" {
+		parts = append(parts, "UNTIL="+r.Until)
+	}
+	return RRulePrefix + strings.Join(parts, ";")
+}
+
+// parseWeekdays разбирает список дней недели правила "w" (1 — понедельник, 7 — воскресенье)
+func parseWeekdays(list string) ([]RepeatDay, error) {
+	var weekdays []RepeatDay
+	for _, part := range strings.Split(list, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || day < 1 || day > 7 {
+			return nil, fmt.Errorf("недопустимый день недели: %s", part)
+		}
+		weekdays = append(weekdays, RepeatDay{Weekday: day})
+	}
+	return weekdays, nil
+}
+
+// parseMonthRule разбирает правило "m": список дней месяца (1..31, -1 — последний день,
+// -2 — предпоследний) и необязательный список месяцев (1..12)
+func parseMonthRule(rule string) ([]int, []int, error) {
+	parts := strings.Fields(rule)
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, nil, fmt.Errorf("неверный формат правила m: %s", rule)
+	}
+
+	var days []int
+	for _, part := range strings.Split(parts[0], ",") {
+		day, err := strconv.Atoi(part)
+		if err != nil || day < -2 || day == 0 || day > 31 {
+			return nil, nil, fmt.Errorf("недопустимый день месяца: %s", part)
+		}
+		days = append(days, day)
+	}
+
+	var months []int
+	if len(parts) == 2 {
+		for _, part := range strings.Split(parts[1], ",") {
+			month, err := strconv.Atoi(part)
+			if err != nil || month < 1 || month > 12 {
+				return nil, nil, fmt.Errorf("недопустимый месяц: %s", part)
+			}
+			months = append(months, month)
+		}
+	}
+	return days, months, nil
+}
+
+func (r *RepeatRule) hasWeekday(weekday time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if time.Weekday(d.Weekday%7) == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RepeatRule) hasMonth(month time.Month) bool {
+	for _, m := range r.ByMonth {
+		if time.Month(m) == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RepeatRule) matchesMonthDay(date time.Time) bool {
+	lastDay := daysInMonth(date)
+	for _, day := range r.ByMonthDay {
+		if day == date.Day() || day == date.Day()-lastDay-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func joinInts(values []int) string {
+	items := make([]string, len(values))
+	for i, v := range values {
+		items[i] = strconv.Itoa(v)
+	}
+	return strings.Join(items, ",")
+}
+
+// uniqueInts убирает соседние повторы из отсортированного списка
+func uniqueInts(values []int) []int {
+	var result []int
+	for i, v := range values {
+		if i == 0 || values[i-1] != v {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const RRulePrefix = "RRULE:"
+
+// ErrRepeatFinished возвращается, когда у правила повторения больше нет дат (исчерпаны COUNT или UNTIL)
+var ErrRepeatFinished = errors.New("повторения задачи закончились")
+
+// rruleWeekdays сопоставляет коды дней недели RFC 5545 с номерами от 1 (понедельник) до 7 (воскресенье)
+var rruleWeekdays = map[string]int{
+	"MO": 1,
+	"TU": 2,
+	"WE": 3,
+	"TH": 4,
+	"FR": 5,
+	"SA": 6,
+	"SU": 7,
+}
+
+var rruleWeekdayCodes = []string{"", "MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// parseRRule разбирает правило вида "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"
+func parseRRule(repeat string) (*RepeatRule, error) {
+	body := strings.TrimPrefix(repeat, RRulePrefix)
+	if body == "" {
+		return nil, fmt.Errorf("пустое правило RRULE")
+	}
+
+	r := &RepeatRule{Format: RepeatFormatRRule, Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(body, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("неверный элемент RRULE: %s", part)
+		}
+		name = strings.ToUpper(name)
+		if seen[name] {
+			return nil, fmt.Errorf("повторяющийся параметр RRULE: %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			switch r.Freq {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+			default:
+				return nil, fmt.Errorf("неподдерживаемое значение FREQ: %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err != nil || r.Interval < 1 || r.Interval > 400 {
+				return nil, fmt.Errorf("недопустимое значение INTERVAL: %s", value)
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("недопустимое значение COUNT: %s", value)
+			}
+		case "UNTIL":
+			r.until, err = parseRRuleDate(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = r.until.Format(DateFormat)
+		case "BYDAY":
+			r.ByDay, err = parseRRuleDays(value)
+			if err != nil {
+				return nil, err
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(item)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("недопустимое значение BYMONTHDAY: %s", item)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(value, ",") {
+				month, err := strconv.Atoi(item)
+				if err != nil || month < 1 || month > 12 {
+					return nil, fmt.Errorf("недопустимое значение BYMONTH: %s", item)
+				}
+				r.ByMonth = append(r.ByMonth, month)
+			}
+		default:
+			return nil, fmt.Errorf("неподдерживаемый параметр RRULE: %s", name)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("в правиле RRULE не указан FREQ")
+	}
+	if r.Count > 0 && !r.until.IsZero() {
+		return nil, fmt.Errorf("COUNT и UNTIL нельзя указывать одновременно")
+	}
+	if r.Freq == FreqWeekly && len(r.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY нельзя использовать с FREQ=WEEKLY")
+	}
+	for _, d := range r.ByDay {
+		if d.Ordinal != 0 && r.Freq != FreqMonthly && r.Freq != FreqYearly {
+			return nil, fmt.Errorf("порядковый номер в BYDAY допустим только с FREQ=MONTHLY или FREQ=YEARLY")
+		}
+		if d.Ordinal != 0 && r.Freq == FreqMonthly && (d.Ordinal > 5 || d.Ordinal < -5) {
+			return nil, fmt.Errorf("недопустимый порядковый номер в BYDAY: %d", d.Ordinal)
+		}
+	}
+	return r, nil
+}
+
+func parseRRuleDays(value string) ([]RepeatDay, error) {
+	var days []RepeatDay
+	for _, item := range strings.Split(strings.ToUpper(value), ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("недопустимое значение BYDAY: %s", item)
+		}
+		weekday, ok := rruleWeekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("недопустимое значение BYDAY: %s", item)
+		}
+		day := RepeatDay{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			ordinal, err := strconv.Atoi(prefix)
+			if err != nil || ordinal == 0 || ordinal < -53 || ordinal > 53 {
+				return nil, fmt.Errorf("недопустимое значение BYDAY: %s", item)
+			}
+			day.Ordinal = ordinal
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseRRuleDate принимает UNTIL в виде 20270101 или 20270101T000000Z
+func parseRRuleDate(value string) (time.Time, error) {
+	datePart, _, _ := strings.Cut(value, "T")
+	date, err := time.Parse(DateFormat, datePart)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("недопустимое значение UNTIL: %s", value)
+	}
+	return date, nil
+}
+
+// nextRRule возвращает первую дату серии, которая позже after, и её порядковый номер в серии.
+// Дата начала серии dtstart всегда считается первым повторением.
+func (r *RepeatRule) nextRRule(dtstart time.Time, after time.Time) (time.Time, int, error) {
+	limit := r.searchLimit(after)
+	index := 1
+	for date := dtstart.AddDate(0, 0, 1); date.Before(limit); date = date.AddDate(0, 0, 1) {
+		if !r.until.IsZero() && date.After(r.until) {
+			return time.Time{}, 0, ErrRepeatFinished
+		}
+		if !r.matches(dtstart, date) {
+			continue
+		}
+		index++
+		if r.Count > 0 && index > r.Count {
+			return time.Time{}, 0, ErrRepeatFinished
+		}
+		if date.After(after) {
+			return date, index, nil
+		}
+	}
+	return time.Time{}, 0, fmt.Errorf("правило RRULE не задаёт ни одной существующей даты")
+}
+
+// searchLimit ограничивает перебор дат: за восемь периодов (и не меньше восьми лет)
+// любое корректное правило даёт хотя бы одну дату
+func (r *RepeatRule) searchLimit(after time.Time) time.Time {
+	var limit time.Time
+	switch r.Freq {
+	case FreqDaily:
+		limit = after.AddDate(0, 0, 8*r.Interval)
+	case FreqWeekly:
+		limit = after.AddDate(0, 0, 56*r.Interval)
+	case FreqMonthly:
+		limit = after.AddDate(0, 8*r.Interval, 0)
+	default:
+		limit = after.AddDate(8*r.Interval, 0, 0)
+	}
+	if minLimit := after.AddDate(8, 0, 0); limit.Before(minLimit) {
+		limit = minLimit
+	}
+	return limit
+}
+
+func (r *RepeatRule) matches(dtstart time.Time, date time.Time) bool {
+	if !r.inActivePeriod(dtstart, date) {
+		return false
+	}
+	if len(r.ByMonth) > 0 && !r.hasMonth(date.Month()) {
+		return false
+	}
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		switch r.Freq {
+		case FreqWeekly:
+			return date.Weekday() == dtstart.Weekday()
+		case FreqMonthly:
+			return date.Day() == dtstart.Day()
+		case FreqYearly:
+			return date.Day() == dtstart.Day() && (len(r.ByMonth) > 0 || date.Month() == dtstart.Month())
+		}
+		return true
+	}
+
+	if len(r.ByMonthDay) > 0 && !r.matchesMonthDay(date) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchesDay(date) {
+		return false
+	}
+	return true
+}
+
+func (r *RepeatRule) inActivePeriod(dtstart time.Time, date time.Time) bool {
+	var periods int
+	switch r.Freq {
+	case FreqDaily:
+		periods = daysBetween(dtstart, date)
+	case FreqWeekly:
+		periods = daysBetween(weekStart(dtstart), weekStart(date)) / 7
+	case FreqMonthly:
+		periods = (date.Year()-dtstart.Year())*12 + int(date.Month()-dtstart.Month())
+	case FreqYearly:
+		periods = date.Year() - dtstart.Year()
+	}
+	return periods%r.Interval == 0
+}
+
+func (r *RepeatRule) matchesDay(date time.Time) bool {
+	for _, d := range r.ByDay {
+		if time.Weekday(d.Weekday%7) != date.Weekday() {
+			continue
+		}
+		if d.Ordinal == 0 {
+			return true
+		}
+
+		// порядковый номер считается внутри месяца, а для FREQ=YEARLY без BYMONTH — внутри года
+		position, total := date.Day(), daysInMonth(date)
+		if r.Freq == FreqYearly && len(r.ByMonth) == 0 {
+			position, total = date.YearDay(), daysInYear(date)
+		}
+		if d.Ordinal > 0 && (position-1)/7+1 == d.Ordinal {
+			return true
+		}
+		if d.Ordinal < 0 && (total-position)/7+1 == -d.Ordinal {
+			return true
+		}
+	}
+	return false
+}
+
+func daysBetween(from time.Time, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// weekStart возвращает понедельник недели, в которую попадает дата (WKST=MO)
+func weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+}
+
+func daysInMonth(date time.Time) int {
+	return time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysInYear(date time.Time) int {
+	return time.Date(date.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
@@ -1,8 +1,9 @@
 package service
 
 import (
+	"errors"
 	"fmt"
-	"strconv"
+	"go_final_project/model"
 	"strings"
 	"time"
 )
@@ -15,26 +16,244 @@ func NextDate(now time.Time, dateStr string, repeat string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("неверная дата: %v", err)
 	}
+	rule, err := ParseRepeatRule(repeat)
+	if err != nil {
+		return "", err
+	}
+
+	next, err := rule.Next(now, taskDate)
+	if err != nil {
+		return "", err
+	}
+	return next.Format(DateFormat), nil
+}
+
+// Next возвращает дату, на которую переносится задача с датой taskDate при её выполнении в момент now
+func (r *RepeatRule) Next(now time.Time, taskDate time.Time) (time.Time, error) {
+	switch {
+	case r.Format == RepeatFormatRRule:
+		next, _, err := r.nextRRuleDate(now, taskDate)
+		return next, err
+	case r.Freq == FreqWeekly:
+		return r.nextWeekday(now, taskDate), nil
+	case r.Freq == FreqMonthly:
+		return r.nextMonthDay(now, taskDate)
+	}
 
-	if repeat == "d 1" && !taskDate.After(now) {
-		return now.Format(DateFormat), nil
+	if r.Freq == FreqDaily && r.Interval == 1 && !taskDate.After(now) {
+		return now, nil
 	}
 
 	for {
-		if repeat == "y" {
+		if r.Freq == FreqYearly {
 			taskDate = taskDate.AddDate(1, 0, 0)
-		} else if strings.HasPrefix(repeat, "d ") {
-			daysStr := strings.TrimPrefix(repeat, "d ")
-			days, err := strconv.Atoi(daysStr)
-			if err != nil || days < 1 || days > 400 {
-				return "", fmt.Errorf("наверное количество дней: %v", days)
-			}
-			taskDate = taskDate.AddDate(0, 0, days)
 		} else {
-			return "", fmt.Errorf("неподдерживаемое правило повторения: %s", repeat)
+			taskDate = taskDate.AddDate(0, 0, r.Interval)
 		}
 		if taskDate.After(now) {
-			return taskDate.Format(DateFormat), nil
+			return taskDate, nil
+		}
+	}
+}
+
+// NextDates возвращает до count ближайших дат повторения задачи, начиная с первой даты после now.
+// Перебор останавливается на дате позже until (если она задана) или когда повторения закончились.
+func NextDates(now time.Time, dateStr string, repeat string, count int, until time.Time) ([]string, error) {
+	dates := []string{}
+	cursor := now
+	for len(dates) < count {
+		next, err := NextDate(cursor, dateStr, repeat)
+		if errors.Is(err, ErrRepeatFinished) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(dates) > 0 && next <= dates[len(dates)-1] {
+			// "d 1" для прошедшей даты возвращает сам now, поэтому сдвигаемся на день вперёд
+			cursor = cursor.AddDate(0, 0, 1)
+			continue
+		}
+
+		nextDate, err := time.Parse(DateFormat, next)
+		if err != nil {
+			return nil, err
+		}
+		if !until.IsZero() && nextDate.After(until) {
+			break
+		}
+		dates = append(dates, next)
+		cursor = nextDate
+	}
+	return dates, nil
+}
+
+// nextWeekday возвращает ближайшую дату из списка дней недели, которая позже и now, и даты задачи
+func (r *RepeatRule) nextWeekday(now time.Time, taskDate time.Time) time.Time {
+	start := taskDate
+	if now.After(start) {
+		start = now
+	}
+	date := start.AddDate(0, 0, 1)
+	for !r.hasWeekday(date.Weekday()) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// nextMonthDay возвращает ближайшую подходящую под правило "m" дату, которая позже и now, и даты задачи
+func (r *RepeatRule) nextMonthDay(now time.Time, taskDate time.Time) (time.Time, error) {
+	start := taskDate
+	if now.After(start) {
+		start = now
+	}
+	date := start.AddDate(0, 0, 1)
+	// за четыре года встречаются все месяцы и все дни, включая 29 февраля
+	for limit := start.AddDate(4, 0, 1); date.Before(limit); date = date.AddDate(0, 0, 1) {
+		if len(r.ByMonth) > 0 && !r.hasMonth(date.Month()) {
+			continue
+		}
+		if r.matchesMonthDay(date) {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("правило m не задаёт ни одной существующей даты")
+}
+
+// AdvanceRepeat рассчитывает следующую дату задачи при её выполнении и возвращает правило,
+// которое нужно сохранить вместе с ней: у RRULE с COUNT уменьшается число оставшихся повторений.
+// Если повторений больше нет, возвращается ErrRepeatFinished.
+func AdvanceRepeat(now time.Time, dateStr string, repeat string) (string, string, error) {
+	if !strings.HasPrefix(repeat, RRulePrefix) {
+		next, err := NextDate(now, dateStr, repeat)
+		return next, repeat, err
+	}
+
+	taskDate, err := time.Parse(DateFormat, dateStr)
+	if err != nil {
+		return "", "", fmt.Errorf("неверная дата: %v", err)
+	}
+	rule, err := ParseRepeatRule(repeat)
+	if err != nil {
+		return "", "", err
+	}
+	next, index, err := rule.nextRRuleDate(now, taskDate)
+	if err != nil {
+		return "", "", err
+	}
+	if rule.Count > 0 {
+		rule.Count -= index - 1
+	}
+	return next.Format(DateFormat), rule.String(), nil
+}
+
+// CompleteTask отмечает выполнение задачи: переносит повторяющуюся задачу на следующую дату
+// и увеличивает число выполнений. Возвращает true, если задача больше не повторяется —
+// она разовая, правило исчерпано, достигнут MaxCount или следующая дата позже EndDate.
+func CompleteTask(now time.Time, task *model.Tasks) (bool, error) {
+	task.DoneCount++
+	if task.Repeat == "" {
+		return true, nil
+	}
+	if task.MaxCount > 0 && task.DoneCount >= task.MaxCount {
+		return true, nil
+	}
+	return advanceTask(now, task)
+}
+
+// SkipTask переносит повторяющуюся задачу на следующее повторение, не засчитывая выполнение.
+// Возвращает исключение для пропущенного повторения и true, если следующего повторения нет.
+func SkipTask(now time.Time, task *model.Tasks) (model.TaskException, bool, error) {
+	if task.Repeat == "" {
+		return model.TaskException{}, false, &ValidationError{Field: "repeat", Message: "Пропустить можно только повторяющуюся задачу"}
+	}
+	exception := model.TaskException{TaskID: task.ID, Date: occurrenceDate(task)}
+	next, finished, err := nextOccurrence(now, *task)
+	if err != nil {
+		return model.TaskException{}, false, err
+	}
+	*task = next
+	return exception, finished, nil
+}
+
+// MoveTask переносит текущее повторение задачи на другую дату. Правило остаётся привязанным
+// к исходной дате повторения: она сохраняется в AnchorDate, и следующая дата считается от неё.
+// Перенести повторение можно не раньше сегодняшнего дня и только раньше следующего повторения.
+func MoveTask(now time.Time, task *model.Tasks, dateStr string) (model.TaskException, error) {
+	if task.Repeat == "" {
+		return model.TaskException{}, &ValidationError{Field: "repeat", Message: "Перенести отдельное повторение можно только у повторяющейся задачи"}
+	}
+	if _, err := time.Parse(DateFormat, dateStr); err != nil {
+		return model.TaskException{}, &ValidationError{Field: "date", Message: fmt.Sprintf("некорректная дата. Ожидается формат 20060102: %v", err)}
+	}
+	if dateStr < now.Format(DateFormat) {
+		return model.TaskException{}, &ValidationError{Field: "date", Message: "Нельзя перенести повторение на прошедшую дату"}
+	}
+	next, finished, err := nextOccurrence(now, *task)
+	if err != nil {
+		return model.TaskException{}, err
+	}
+	if !finished && dateStr >= next.Date {
+		return model.TaskException{}, &ValidationError{Field: "date", Message: "Повторение можно перенести только на дату раньше следующего повторения " + next.Date}
+	}
+
+	exception := model.TaskException{TaskID: task.ID, Date: occurrenceDate(task), MovedTo: dateStr}
+	task.AnchorDate = exception.Date
+	task.Date = dateStr
+	if task.AnchorDate == task.Date {
+		task.AnchorDate = ""
+	}
+	return exception, nil
+}
+
+// nextOccurrence возвращает задачу, перенесённую на повторение строго после текущего, и true,
+// если его нет. Дата считается не раньше текущего повторения: у будущего повторения "d 1"
+// иначе получилась бы та же дата, а у "d 1" на сегодня — сегодняшняя.
+func nextOccurrence(now time.Time, task model.Tasks) (model.Tasks, bool, error) {
+	current := occurrenceDate(&task)
+	from := now
+	if date, err := time.Parse(DateFormat, current); err == nil && date.After(from) {
+		from = date
+	}
+	for {
+		next := task
+		finished, err := advanceTask(from, &next)
+		if err != nil || finished || next.Date > current {
+			return next, finished, err
 		}
+		from = from.AddDate(0, 0, 1)
+	}
+}
+
+// occurrenceDate возвращает дату повторения по правилу, которой соответствует текущая дата задачи
+func occurrenceDate(task *model.Tasks) string {
+	if task.AnchorDate != "" {
+		return task.AnchorDate
+	}
+	return task.Date
+}
+
+func advanceTask(now time.Time, task *model.Tasks) (bool, error) {
+	next, repeat, err := AdvanceRepeat(now, occurrenceDate(task), task.Repeat)
+	if errors.Is(err, ErrRepeatFinished) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if task.EndDate != "" && next > task.EndDate {
+		return true, nil
+	}
+	task.Date = next
+	task.Repeat = repeat
+	task.AnchorDate = ""
+	return false, nil
+}
+
+// nextRRuleDate считает дату задачи началом серии RRULE и ищет повторение позже now и этой даты
+func (r *RepeatRule) nextRRuleDate(now time.Time, taskDate time.Time) (time.Time, int, error) {
+	after := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if taskDate.After(after) {
+		after = taskDate
 	}
+	return r.nextRRule(taskDate, after)
 }
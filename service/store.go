@@ -0,0 +1,70 @@
+package service
+
+import (
+	"go_final_project/model"
+	"time"
+)
+
+// TaskStore — хранилище задач, их истории, исключений из повторений, журнала аудита и токенов отмены.
+// Реализации: TaskRepository (SQLite или PostgreSQL) и MemoryStore (в памяти, для тестов обработчиков).
+//
+// Методы, которые ищут задачу по id, принимают пользователя и не находят задачи, которых он
+// не видит; методы, которые меняют задачу, не находят и задач, которые он не может менять.
+// Если запись не найдена, возвращается sql.ErrNoRows.
+type TaskStore interface {
+	CreateTask(actor Actor, task model.Tasks) (int64, error)
+	GetTaskByID(userID int64, id int) (model.Tasks, error)
+	GetTaskWithArchived(userID int64, id int) (model.Tasks, error)
+	UpdateTask(actor Actor, task model.Tasks) (int64, error)
+	RescheduleTask(actor Actor, task model.Tasks, exception model.TaskException) error
+	CompleteTask(actor Actor, task model.Tasks, completion model.TaskCompletion, finished bool) error
+	ArchiveTask(actor Actor, id int, archivedAt string) (int64, error)
+	DeleteTask(actor Actor, id int) (int64, error)
+	ListTasks(opts TaskListOptions) (TaskPage, error)
+
+	GetTrash(userID int64) ([]model.Tasks, error)
+	GetTrashedTask(userID int64, id int) (model.Tasks, error)
+	RestoreTask(actor Actor, id int) (int64, error)
+	PurgeTask(actor Actor, id int) (int64, error)
+	PurgeTrash(deletedBefore time.Time) (int64, error)
+
+	GetTaskCompletions(userID int64, taskID int) ([]model.TaskCompletion, error)
+	GetTaskExceptions(userID int64, taskID int) ([]model.TaskException, error)
+
+	GetAuditLog(filter AuditFilter) (AuditPage, error)
+
+	SaveUndo(userID int64, token string, action string, snapshot UndoSnapshot, expiresAt time.Time) error
+	Undo(actor Actor, token string, now time.Time) (model.Tasks, error)
+}
+
+// UserStore — хранилище пользователей и их личных токенов
+type UserStore interface {
+	CreateUser(login string, passwordHash string) (int64, error)
+	GetUserByLogin(login string) (model.User, error)
+	GetUserByID(id int64) (model.User, error)
+
+	CreateAPIToken(userID int64, token model.APIToken, hash string) (model.APIToken, error)
+	GetAPITokens(userID int64) ([]model.APIToken, error)
+	GetAPITokenByHash(hash string, now time.Time) (model.APIToken, error)
+	DeleteAPIToken(userID int64, id int) error
+}
+
+// ListStore — хранилище общих списков и их участников
+type ListStore interface {
+	CreateList(userID int64, list model.List) (model.List, error)
+	GetLists(userID int64) ([]model.List, error)
+	GetRole(userID int64, listID int64) (string, error)
+	GetMembers(listID int64) ([]model.ListMember, error)
+	SetMember(listID int64, userID int64, role string) error
+	RemoveMember(listID int64, userID int64) error
+}
+
+var (
+	_ TaskStore = (*TaskRepository)(nil)
+	_ UserStore = (*UserRepository)(nil)
+	_ ListStore = (*ListRepository)(nil)
+
+	_ TaskStore = (*MemoryStore)(nil)
+	_ UserStore = (*MemoryStore)(nil)
+	_ ListStore = (*MemoryStore)(nil)
+)
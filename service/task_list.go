@@ -0,0 +1,313 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_final_project/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SortByDate    = "date"
+	SortByTitle   = "title"
+	SortByID      = "id"
+	SortByCreated = "created"
+)
+
+// taskSortColumns сопоставляет вариант сортировки списка задач со столбцом таблицы scheduler
+var taskSortColumns = map[string]string{
+	SortByDate:    "date",
+	SortByTitle:   "title",
+	SortByID:      "id",
+	SortByCreated: "created_at",
+}
+
+// ErrInvalidCursor возвращается, если курсор повреждён или получен для другой сортировки или строки поиска
+var ErrInvalidCursor = errors.New("некорректный курсор")
+
+// TaskListOptions — параметры списка задач: строка поиска, фильтры, сортировка, размер страницы
+// и курсор из предыдущего ответа
+type TaskListOptions struct {
+	// UserID — пользователь, чьи личные задачи выводятся
+	UserID int64
+	// ListID — список, задачи которого выводятся вместо личных; право их видеть проверяет вызывающий
+	ListID int64
+	Search string
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+
+	// From и To — границы дат в формате DateFormat включительно, пустая строка — без границы
+	From string
+	To   string
+	// Overdue оставляет задачи с датой раньше Today
+	Overdue bool
+	Today   string
+	// Repeating отбирает только повторяющиеся (true) или только разовые (false) задачи
+	Repeating *bool
+}
+
+// filter возвращает условия фильтров для WHERE. Условия на дату сравнивают сам столбец date,
+// поэтому SQLite использует индекс scheduler_date.
+func (opts TaskListOptions) filter() (string, []any) {
+	conditions := []string{"list_id = 0 AND user_id = ?"}
+	args := []any{opts.UserID}
+	if opts.ListID != 0 {
+		conditions = []string{"list_id = ?"}
+		args = []any{opts.ListID}
+	}
+	if opts.From != "" {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, opts.From)
+	}
+	if opts.To != "" {
+		conditions = append(conditions, "date <= ?")
+		args = append(args, opts.To)
+	}
+	if opts.Overdue {
+		conditions = append(conditions, "date < ?")
+		args = append(args, opts.Today)
+	}
+	if opts.Repeating != nil {
+		if *opts.Repeating {
+			conditions = append(conditions, "repeat != ''")
+		} else {
+			conditions = append(conditions, "repeat = ''")
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// filterKey описывает фильтры в курсоре, чтобы курсор нельзя было применить к другой выборке
+func (opts TaskListOptions) filterKey() string {
+	repeating := ""
+	if opts.Repeating != nil {
+		repeating = strconv.FormatBool(*opts.Repeating)
+	}
+	overdue := ""
+	if opts.Overdue {
+		overdue = opts.Today
+	}
+	return strings.Join([]string{strconv.FormatInt(opts.ListID, 10), opts.From, opts.To, overdue, repeating}, "|")
+}
+
+// TaskPage — страница списка задач. Курсоры пусты, если дальше в эту сторону задач нет.
+type TaskPage struct {
+	Tasks      []model.Tasks
+	NextCursor string
+	PrevCursor string
+}
+
+// taskCursor — содержимое курсора. Для списка и поиска по дате в нём хранится ключ сортировки
+// и id крайней задачи страницы, для полнотекстового поиска, где порядок задаёт релевантность, — смещение.
+// Клиент получает курсор закодированным и не должен разбирать его.
+type taskCursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Search string `json:"q,omitempty"`
+	Filter string `json:"f,omitempty"`
+	Key    string `json:"k,omitempty"`
+	ID     int64  `json:"i,omitempty"`
+	Before bool   `json:"b,omitempty"`
+	Offset int    `json:"o,omitempty"`
+}
+
+func IsValidTaskSort(sort string) bool {
+	_, ok := taskSortColumns[sort]
+	return ok
+}
+
+func (c taskCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(value string, opts TaskListOptions) (*taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c taskCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != opts.Sort || c.Desc != opts.Desc || c.Search != opts.Search || c.Filter != opts.filterKey() || c.Offset < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ListTasks возвращает страницу активных задач. Строка поиска вида "08.02.2024" отбирает задачи
+// на эту дату, любая другая ищет по тексту — тогда задачи упорядочены по релевантности, а сортировка
+// из opts не применяется.
+func (r *TaskRepository) ListTasks(opts TaskListOptions) (TaskPage, error) {
+	var cursor *taskCursor
+	if opts.Cursor != "" {
+		var err error
+		if cursor, err = decodeTaskCursor(opts.Cursor, opts); err != nil {
+			return TaskPage{}, err
+		}
+	}
+
+	filter, args := opts.filter()
+	if opts.Search != "" {
+		if date, err := time.Parse(SearchDateFormat, opts.Search); err == nil {
+			return r.listTasks(opts, cursor, joinConditions(filter, "date = ?"), append(args, date.Format(DateFormat))...)
+		}
+		if terms := parseSearchQuery(opts.Search); len(terms) > 0 {
+			return r.searchTasks(opts, cursor, terms, filter, args...)
+		}
+	}
+	return r.listTasks(opts, cursor, filter, args...)
+}
+
+// joinConditions соединяет условия WHERE через AND, пропуская пустые
+func joinConditions(conditions ...string) string {
+	var parts []string
+	for _, c := range conditions {
+		if c != "" {
+			parts = append(parts, c)
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// listTasks листает задачи по ключу сортировки: следующая страница начинается после последней
+// задачи текущей, поэтому добавленные и удалённые задачи не сдвигают страницы.
+// Задачи с одинаковым ключом упорядочены по id.
+func (r *TaskRepository) listTasks(opts TaskListOptions, cursor *taskCursor, filter string, filterArgs ...any) (TaskPage, error) {
+	column := taskSortColumns[opts.Sort]
+	before := cursor != nil && cursor.Before
+
+	// к предыдущей странице идём в обратном порядке, а потом разворачиваем результат
+	op, direction := ">", "ASC"
+	if opts.Desc != before {
+		op, direction = "<", "DESC"
+	}
+
+	where := activeTask
+	args := filterArgs
+	if filter != "" {
+		where += " AND " + filter
+	}
+	if cursor != nil {
+		if column == "id" {
+			where += " AND id " + op + " ?"
+			args = append(args, cursor.ID)
+		} else {
+			where += fmt.Sprintf(" AND (%s %s ? OR (%s = ? AND id %s ?))", column, op, column, op)
+			args = append(args, cursor.Key, cursor.Key, cursor.ID)
+		}
+	}
+	order := column + " " + direction
+	if column != "id" {
+		order += ", id " + direction
+	}
+
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE " + where + " ORDER BY " + order + " LIMIT ?"
+	rows, err := r.DB.Query(query, append(args, opts.Limit+1)...)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Tasks
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return TaskPage{}, err
+	}
+	return keysetPage(opts, cursor, tasks), nil
+}
+
+// keysetPage собирает страницу из задач, выбранных по ключу сортировки от курсора: не больше
+// opts.Limit+1 задач в порядке обхода (к предыдущей странице — в обратном)
+func keysetPage(opts TaskListOptions, cursor *taskCursor, tasks []model.Tasks) TaskPage {
+	before := cursor != nil && cursor.Before
+	more := len(tasks) > opts.Limit
+	if more {
+		tasks = tasks[:opts.Limit]
+	}
+	if before {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+	}
+
+	page := TaskPage{Tasks: tasks}
+	if len(tasks) == 0 {
+		return page
+	}
+	// страница, открытая по курсору, всегда может вернуться туда, откуда пришли
+	if more || before {
+		page.NextCursor = keyCursor(opts, tasks[len(tasks)-1], false)
+	}
+	if (before && more) || (!before && cursor != nil) {
+		page.PrevCursor = keyCursor(opts, tasks[0], true)
+	}
+	return page
+}
+
+func keyCursor(opts TaskListOptions, task model.Tasks, before bool) string {
+	id, _ := strconv.ParseInt(task.ID, 10, 64)
+	c := taskCursor{Sort: opts.Sort, Desc: opts.Desc, Search: opts.Search, Filter: opts.filterKey(), ID: id, Before: before}
+	switch opts.Sort {
+	case SortByDate:
+		c.Key = task.Date
+	case SortByTitle:
+		c.Key = task.Title
+	case SortByCreated:
+		c.Key = task.CreatedAt
+	}
+	return c.encode()
+}
+
+// searchTasks листает результаты текстового поиска по смещению: порядок по релевантности
+// нельзя продолжить от ключа последней задачи
+func (r *TaskRepository) searchTasks(opts TaskListOptions, cursor *taskCursor, terms []searchTerm, filter string, filterArgs ...any) (TaskPage, error) {
+	offset := 0
+	if cursor != nil {
+		offset = cursor.Offset
+	}
+
+	var tasks []model.Tasks
+	var err error
+	if r.fullText {
+		tasks, err = r.searchTasksFullText(terms, filter, filterArgs, opts.Limit+1, offset)
+	} else {
+		tasks, err = r.searchTasksByText(terms, filter, filterArgs, opts.Limit+1, offset)
+	}
+	if err != nil {
+		return TaskPage{}, err
+	}
+	return offsetPage(opts, offset, tasks), nil
+}
+
+// offsetPage собирает страницу из не больше чем opts.Limit+1 найденных задач, начиная со смещения offset
+func offsetPage(opts TaskListOptions, offset int, tasks []model.Tasks) TaskPage {
+	page := TaskPage{Tasks: tasks}
+	base := taskCursor{Sort: opts.Sort, Desc: opts.Desc, Search: opts.Search, Filter: opts.filterKey()}
+	if len(tasks) > opts.Limit {
+		page.Tasks = tasks[:opts.Limit]
+		next := base
+		next.Offset = offset + opts.Limit
+		page.NextCursor = next.encode()
+	}
+	if offset > 0 {
+		prev := base
+		prev.Offset = max(offset-opts.Limit, 0)
+		prev.Before = true
+		page.PrevCursor = prev.encode()
+	}
+	return page
+}
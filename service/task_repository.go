@@ -2,56 +2,200 @@ package service
 
 import (
 	"database/sql"
+	"errors"
 	"go_final_project/model"
+	"time"
 )
 
+const taskColumns = "id, date, title, comment, repeat, end_date, max_count, done_count, anchor_date, completed_at, created_at, user_id, list_id, deleted_at, version"
+
+// ErrVersionConflict — задачу успели изменить после того, как её прочитали для этого изменения
+var ErrVersionConflict = errors.New("задача изменена другим запросом")
+
+// notDeleted отбирает задачи, которых нет в корзине
+const notDeleted = "deleted_at = ''"
+
+// activeTask отбирает задачи, которые ещё не выполнены окончательно и не удалены
+const activeTask = "completed_at = '' AND " + notDeleted
+
+// visibleTask отбирает задачи, которые видит пользователь: его личные и задачи списков,
+// в которых он состоит. Пользователь подставляется в оба параметра.
+const visibleTask = "(list_id = 0 AND user_id = ? OR list_id IN (SELECT list_id FROM list_members WHERE user_id = ?))"
+
+// editableTask отбирает задачи, которые пользователь может менять: его личные и задачи
+// списков, где у него роль editor или owner
+const editableTask = "(list_id = 0 AND user_id = ? OR list_id IN (SELECT list_id FROM list_members WHERE user_id = ? AND role IN ('" +
+	RoleEditor + "', '" + RoleOwner + "')))"
+
+// ownTask ограничивает записи из task_exceptions и task_completions задачами, которые видит пользователь
+const ownTask = "task_id IN (SELECT id FROM scheduler WHERE " + visibleTask + ")"
+
 type TaskRepository struct {
-	DB *sql.DB
+	DB *DB
+	// fullText — в базе есть полнотекстовый индекс scheduler_search (в SQLite он есть всегда)
+	fullText bool
 }
 
-func NewTaskRepository(db *sql.DB) *TaskRepository {
-	return &TaskRepository{DB: db}
+func NewTaskRepository(db *DB) *TaskRepository {
+	return &TaskRepository{DB: db, fullText: hasFullTextIndex(db)}
 }
 
-func (r *TaskRepository) CreateTask(task model.Tasks) (int64, error) {
-	query := "INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)"
-	result, err := r.DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// execer — общее у *DB и *Tx, чтобы запросы можно было выполнять и внутри транзакции
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
 }
 
-func (r *TaskRepository) GetTaskByID(id int) (model.Tasks, error) {
+// scanTask читает столбцы taskColumns и, если запрос возвращает что-то ещё, следующие за ними значения в extra
+func scanTask(row rowScanner, extra ...any) (model.Tasks, error) {
 	var task model.Tasks
-	query := "SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?"
-	err := r.DB.QueryRow(query, id).Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	dest := []any{&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat,
+		&task.EndDate, &task.MaxCount, &task.DoneCount, &task.AnchorDate, &task.CompletedAt, &task.CreatedAt, &task.UserID, &task.ListID, &task.DeletedAt, &task.Version}
+	err := row.Scan(append(dest, extra...)...)
 	return task, err
 }
 
-func (r *TaskRepository) UpdateTask(task model.Tasks) (int64, error) {
-	query := "UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?"
-	result, err := r.DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
+// Все методы репозитория, которые ищут задачу по id, принимают userID пользователя:
+// задача, которую он не видит, для них не отличается от несуществующей. Методы, которые
+// меняют задачу, находят только задачи, где у пользователя есть право на изменение.
+// Создание, изменение, удаление и выполнение задачи записываются в журнал аудита в той же
+// транзакции, поэтому эти методы принимают Actor.
+
+// CreateTask создаёт задачу пользователя actor.UserID в списке task.ListID; право создавать
+// задачи в списке проверяет вызывающий
+func (r *TaskRepository) CreateTask(actor Actor, task model.Tasks) (int64, error) {
+	tx, err := r.DB.Begin()
 	if err != nil {
 		return 0, err
 	}
-	affectedRows, err := result.RowsAffected()
-	return affectedRows, err
+	defer tx.Rollback()
+
+	query := `INSERT INTO scheduler (date, title, comment, repeat, end_date, max_count, created_at, user_id, list_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	var id int64
+	err = tx.QueryRow(query, task.Date, task.Title, task.Comment, task.Repeat, task.EndDate, task.MaxCount,
+		time.Now().UTC().Format(time.RFC3339), actor.UserID, task.ListID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	created, err := getTaskTx(tx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeAudit(tx, actor, AuditActionCreate, nil, &created); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-func (r *TaskRepository) UpdateTaskDate(id int, nextDate string) (int64, error) {
-	query := "UPDATE scheduler SET date = ? WHERE id = ?"
-	result, err := r.DB.Exec(query, nextDate, id)
+func (r *TaskRepository) GetTaskByID(userID int64, id int) (model.Tasks, error) {
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE id = ? AND " + visibleTask + " AND " + activeTask
+	return scanTask(r.DB.QueryRow(query, id, userID, userID))
+}
+
+// GetTaskWithArchived возвращает задачу, даже если она уже выполнена и находится в архиве.
+// Задачи из корзины не возвращаются.
+func (r *TaskRepository) GetTaskWithArchived(userID int64, id int) (model.Tasks, error) {
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE id = ? AND " + visibleTask + " AND " + notDeleted
+	return scanTask(r.DB.QueryRow(query, id, userID, userID))
+}
+
+// UpdateTask сохраняет отредактированную задачу, если её версия всё ещё task.Version; иначе
+// ничего не меняет и возвращает 0. Если дата изменилась, перенос отдельного повторения
+// теряет смысл, и привязка к исходной дате сбрасывается.
+func (r *TaskRepository) UpdateTask(actor Actor, task model.Tasks) (int64, error) {
+	query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, end_date = ?, max_count = ?,
+		anchor_date = CASE WHEN date = ? THEN anchor_date ELSE '' END, version = version + 1
+		WHERE id = ? AND version = ? AND ` + editableTask + " AND " + activeTask
+	return r.changeTask(actor, AuditActionUpdate, task.ID, query, task.Date, task.Title, task.Comment, task.Repeat,
+		task.EndDate, task.MaxCount, task.Date, task.ID, task.Version, actor.UserID, actor.UserID)
+}
+
+// changeTask выполняет запрос query, который меняет задачу id, и, если он что-то изменил,
+// записывает в журнал аудита задачу до и после изменения
+func (r *TaskRepository) changeTask(actor Actor, action string, id any, query string, args ...any) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := getTaskTx(tx, id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		return 0, err
 	}
 	affectedRows, err := result.RowsAffected()
-	return affectedRows, err
+	if err != nil || affectedRows == 0 {
+		return 0, err
+	}
+	after, err := getTaskTx(tx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeAudit(tx, actor, action, &before, &after); err != nil {
+		return 0, err
+	}
+	return affectedRows, tx.Commit()
+}
+
+// RescheduleTask в одной транзакции сохраняет пропуск или перенос повторения: новую дату
+// задачи, исключение exception и запись журнала аудита. Задача должна быть получена через
+// GetTaskByID; если после чтения её успели изменить, ничего не меняет и возвращает ErrVersionConflict.
+func (r *TaskRepository) RescheduleTask(actor Actor, task model.Tasks, exception model.TaskException) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getTaskTx(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := updateTaskSchedule(tx, task)
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrVersionConflict
+	}
+	if err := saveTaskException(tx, exception); err != nil {
+		return err
+	}
+	after, err := getTaskTx(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeAudit(tx, actor, rescheduleAction(exception), &before, &after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rescheduleAction — действие для журнала аудита: пропуск, если повторение никуда не перенесено
+func rescheduleAction(exception model.TaskException) string {
+	if exception.MovedTo == "" {
+		return AuditActionSkip
+	}
+	return AuditActionMove
 }
 
-func (r *TaskRepository) DeleteTask(id int) (int64, error) {
-	query := "DELETE FROM scheduler WHERE id = ?"
-	result, err := r.DB.Exec(query, id)
+// updateTaskSchedule сохраняет результат выполнения, пропуска или переноса повторения:
+// новую дату, правило повторения (у RRULE с COUNT оно меняется), число выполнений и привязку.
+// Владелец берётся из task.UserID, а если версия задачи уже не task.Version, возвращается 0.
+func updateTaskSchedule(db execer, task model.Tasks) (int64, error) {
+	query := `UPDATE scheduler SET date = ?, repeat = ?, done_count = ?, anchor_date = ?, version = version + 1
+		WHERE id = ? AND user_id = ? AND version = ?`
+	result, err := db.Exec(query, task.Date, task.Repeat, task.DoneCount, task.AnchorDate, task.ID, task.UserID, task.Version)
 	if err != nil {
 		return 0, err
 	}
@@ -59,21 +203,109 @@ func (r *TaskRepository) DeleteTask(id int) (int64, error) {
 	return affectedRows, err
 }
 
-func (r *TaskRepository) GetAllTasks(limit int) ([]model.Tasks, error) {
-	rows, err := r.DB.Query("SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date LIMIT ?", limit)
+// CompleteTask в одной транзакции записывает выполнение в историю и сохраняет задачу:
+// повторяющуюся — с новой датой, а выполненную окончательно (finished) — переносит в архив.
+// Если версия задачи уже не task.Version, ничего не меняет и возвращает ErrVersionConflict.
+func (r *TaskRepository) CompleteTask(actor Actor, task model.Tasks, completion model.TaskCompletion, finished bool) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getTaskTx(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	query := "INSERT INTO task_completions (task_id, date, done_at) VALUES (?, ?, ?)"
+	if _, err := tx.Exec(query, completion.TaskID, completion.Date, completion.DoneAt); err != nil {
+		return err
+	}
+
+	var affectedRows int64
+	if finished {
+		query = "UPDATE scheduler SET done_count = ?, completed_at = ?, version = version + 1 WHERE id = ? AND user_id = ? AND version = ?"
+		var result sql.Result
+		if result, err = tx.Exec(query, task.DoneCount, completion.DoneAt, task.ID, task.UserID, task.Version); err == nil {
+			affectedRows, err = result.RowsAffected()
+		}
+	} else {
+		affectedRows, err = updateTaskSchedule(tx, task)
+	}
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return ErrVersionConflict
+	}
+	after, err := getTaskTx(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeAudit(tx, actor, AuditActionDone, &before, &after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ArchiveTask переносит в архив задачу, у которой не осталось повторений
+func (r *TaskRepository) ArchiveTask(actor Actor, id int, archivedAt string) (int64, error) {
+	query := "UPDATE scheduler SET completed_at = ?, version = version + 1 WHERE id = ? AND " + editableTask + " AND " + notDeleted
+	return r.changeTask(actor, AuditActionArchive, id, query, archivedAt, id, actor.UserID, actor.UserID)
+}
+
+func (r *TaskRepository) GetTaskCompletions(userID int64, taskID int) ([]model.TaskCompletion, error) {
+	query := "SELECT task_id, date, done_at FROM task_completions WHERE task_id = ? AND " + ownTask + " ORDER BY id"
+	rows, err := r.DB.Query(query, taskID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var completions []model.TaskCompletion
+	for rows.Next() {
+		var completion model.TaskCompletion
+		if err := rows.Scan(&completion.TaskID, &completion.Date, &completion.DoneAt); err != nil {
+			return nil, err
+		}
+		completions = append(completions, completion)
+	}
+
+	return completions, rows.Err()
+}
+
+// DeleteTask переносит задачу в корзину. Переносы и история выполнения остаются при ней,
+// пока задача не будет удалена из корзины окончательно.
+func (r *TaskRepository) DeleteTask(actor Actor, id int) (int64, error) {
+	query := "UPDATE scheduler SET deleted_at = ?, version = version + 1 WHERE id = ? AND " + editableTask + " AND " + notDeleted
+	return r.changeTask(actor, AuditActionDelete, id, query, time.Now().UTC().Format(time.RFC3339), id, actor.UserID, actor.UserID)
+}
+
+// saveTaskException запоминает пропуск или перенос повторения; повторный перенос
+// того же повторения заменяет прежнюю запись
+func saveTaskException(db execer, exception model.TaskException) error {
+	query := `INSERT INTO task_exceptions (task_id, date, moved_to) VALUES (?, ?, ?)
+		ON CONFLICT (task_id, date) DO UPDATE SET moved_to = excluded.moved_to`
+	_, err := db.Exec(query, exception.TaskID, exception.Date, exception.MovedTo)
+	return err
+}
+
+func (r *TaskRepository) GetTaskExceptions(userID int64, taskID int) ([]model.TaskException, error) {
+	query := "SELECT task_id, date, moved_to FROM task_exceptions WHERE task_id = ? AND " + ownTask + " ORDER BY date"
+	rows, err := r.DB.Query(query, taskID, userID, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tasks []model.Tasks
+	var exceptions []model.TaskException
 	for rows.Next() {
-		var task model.Tasks
-		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
+		var exception model.TaskException
+		if err := rows.Scan(&exception.TaskID, &exception.Date, &exception.MovedTo); err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
+		exceptions = append(exceptions, exception)
 	}
 
-	return tasks, rows.Err()
+	return exceptions, rows.Err()
 }
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"encoding/binary"
+	"go_final_project/model"
+	"html"
+	"strings"
+)
+
+const (
+	// FullTextTable — полнотекстовый индекс FTS4 по заголовку и комментарию задачи.
+	//
+	// FTS5 с bm25() здесь не подходит: go-sqlite3 включает его только при сборке с тегом sqlite_fts5,
+	// а в базе с индексом FTS5 сборка без тега не может добавлять и менять задачи — триггеры индекса
+	// ссылаются на неизвестный ей модуль. FTS4 есть в любой сборке; ранжирования в нём нет,
+	// поэтому релевантность считает функция search_rank, которую соединениям SQLite добавляет Open.
+	FullTextTable = "scheduler_search"
+
+	// snippetOpen и snippetClose отмечают найденные слова во фрагменте, который строит SQLite.
+	// Это управляющие символы, которых не бывает в обычном тексте: фрагмент экранируется для HTML,
+	// и только потом отметки заменяются тегами highlightOpen и highlightClose.
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+
+	// highlightOpen и highlightClose выделяют найденные слова во фрагменте, который возвращается в поле snippet
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+)
+
+var snippetHighlighter = strings.NewReplacer(snippetOpen, highlightOpen, snippetClose, highlightClose)
+
+// highlightSnippet экранирует текст задачи во фрагменте и выделяет найденные слова: заголовок
+// и комментарий пишет пользователь, и без экранирования клиент, который вставляет фрагмент как HTML,
+// выполнил бы разметку из них
+func highlightSnippet(snippet string) string {
+	return snippetHighlighter.Replace(html.EscapeString(snippet))
+}
+
+// searchTerm — слово или фраза в кавычках из строки поиска
+type searchTerm struct {
+	text   string
+	phrase bool
+}
+
+// parseSearchQuery делит строку поиска на слова, а текст в двойных кавычках оставляет фразой:
+// `купить "свежее молоко"` — слово «купить» и фраза «свежее молоко»
+func parseSearchQuery(search string) []searchTerm {
+	var terms []searchTerm
+	for i, part := range strings.Split(search, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.Join(strings.Fields(part), " "); phrase != "" {
+				terms = append(terms, searchTerm{text: phrase, phrase: true})
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			terms = append(terms, searchTerm{text: word})
+		}
+	}
+	return terms
+}
+
+// fullTextQuery собирает выражение MATCH для FTS4: каждое слово ищется по префиксу, фраза — целиком,
+// все части должны встретиться в задаче. Пользовательский текст всегда берётся в кавычки,
+// поэтому операторы FTS4 (AND, OR, NEAR, -) в строке поиска не срабатывают.
+func fullTextQuery(terms []searchTerm) string {
+	items := make([]string, len(terms))
+	for i, term := range terms {
+		if term.phrase {
+			items[i] = `"` + term.text + `"`
+		} else {
+			items[i] = `"` + term.text + `*"`
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+// fullTextWeights — вес совпадения в каждом столбце индекса: заголовке и комментарии
+var fullTextWeights = []uint32{10, 1}
+
+// fullTextRank — функция search_rank в SQL. Она считает релевантность по matchinfo(..., 'pcx'): число
+// фраз запроса, число столбцов и по три числа на каждую пару фраза — столбец, первое из которых —
+// совпадения в этой задаче
+func fullTextRank(info []byte) uint32 {
+	if len(info) < 8 {
+		return 0
+	}
+	phrases := binary.NativeEndian.Uint32(info)
+	columns := binary.NativeEndian.Uint32(info[4:])
+	var rank uint32
+	for p := uint32(0); p < phrases; p++ {
+		for c := uint32(0); c < columns && int(c) < len(fullTextWeights); c++ {
+			offset := 8 + 12*(p*columns+c)
+			if int(offset)+4 > len(info) {
+				return rank
+			}
+			rank += fullTextWeights[c] * binary.NativeEndian.Uint32(info[offset:])
+		}
+	}
+	return rank
+}
+
+// hasFullTextIndex проверяет, есть ли индекс FTS4. В PostgreSQL его нет, и поиск работает без индекса.
+func hasFullTextIndex(db *DB) bool {
+	if db.Driver != DriverSQLite {
+		return false
+	}
+	var name string
+	err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", FullTextTable).Scan(&name)
+	return err == nil
+}
+
+// searchTasksFullText ищет по индексу FTS4. Совпадения в заголовке весят больше, чем в комментарии,
+// задачи с одинаковой релевантностью упорядочены по дате. Страницу отбирает сам запрос: page —
+// id задач страницы по убыванию search_rank, и фрагменты с совпадениями SQLite строит только для них.
+func (r *TaskRepository) searchTasksFullText(terms []searchTerm, filter string, filterArgs []any, limit int, offset int) ([]model.Tasks, error) {
+	match := fullTextQuery(terms)
+	query := "WITH page AS (" +
+		"SELECT scheduler.id AS page_id, f.rank AS page_rank FROM scheduler JOIN (" +
+		"SELECT docid, search_rank(matchinfo(" + FullTextTable + ", 'pcx')) AS rank " +
+		"FROM " + FullTextTable + " WHERE " + FullTextTable + " MATCH ?" +
+		") f ON f.docid = scheduler.id WHERE " + joinConditions(activeTask, filter) +
+		" ORDER BY f.rank DESC, date, id LIMIT ? OFFSET ?" +
+		") SELECT " + taskColumns + ", f.snippet FROM page JOIN scheduler ON scheduler.id = page.page_id JOIN (" +
+		"SELECT docid, snippet(" + FullTextTable + ", ?, ?, '…', -1, 12) AS snippet " +
+		"FROM " + FullTextTable + " WHERE " + FullTextTable + " MATCH ? AND docid IN (SELECT page_id FROM page)" +
+		") f ON f.docid = scheduler.id ORDER BY page.page_rank DESC, date, id"
+	args := append([]any{match}, filterArgs...)
+	args = append(args, limit, offset, snippetOpen, snippetClose, match)
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Tasks
+	for rows.Next() {
+		var snippet string
+		task, err := scanTask(rows, &snippet)
+		if err != nil {
+			return nil, err
+		}
+		task.Snippet = highlightSnippet(snippet)
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// likeEscaper экранирует символы шаблона LIKE обратной косой чертой — экранирующим символом
+// PostgreSQL по умолчанию
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern — шаблон ILIKE, который находит text в любом месте строки
+func likePattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
+
+// searchTasksByText — поиск без индекса FTS4. В SQLite индекс создаёт миграция, поэтому без него
+// ищут только в PostgreSQL: каждое слово или фраза должны найтись в заголовке или комментарии,
+// ILIKE не различает регистр и для кириллицы. Задачи упорядочены по дате.
+func (r *TaskRepository) searchTasksByText(terms []searchTerm, filter string, filterArgs []any, limit int, offset int) ([]model.Tasks, error) {
+	conditions := []string{activeTask, filter}
+	args := append([]any{}, filterArgs...)
+	for _, term := range terms {
+		pattern := likePattern(term.text)
+		conditions = append(conditions, "(title ILIKE ? OR comment ILIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE " + joinConditions(conditions...) + " ORDER BY date, id LIMIT ? OFFSET ?"
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Tasks
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// matchesTerms — условие searchTasksByText для MemoryStore
+func matchesTerms(task model.Tasks, terms []searchTerm) bool {
+	title, comment := strings.ToLower(task.Title), strings.ToLower(task.Comment)
+	for _, term := range terms {
+		text := strings.ToLower(term.text)
+		if !strings.Contains(title, text) && !strings.Contains(comment, text) {
+			return false
+		}
+	}
+	return true
+}
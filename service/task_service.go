@@ -1,6 +1,7 @@
 package service
 
 import (
+	"go_final_project/model"
 	"time"
 )
 
@@ -13,3 +14,23 @@ func NewTaskService() *TaskService {
 func (s *TaskService) NextDate(now time.Time, dateStr string, repeat string) (string, error) {
 	return NextDate(now, dateStr, repeat)
 }
+
+func (s *TaskService) CompleteTask(now time.Time, task *model.Tasks) (bool, error) {
+	return CompleteTask(now, task)
+}
+
+func (s *TaskService) SkipTask(now time.Time, task *model.Tasks) (model.TaskException, bool, error) {
+	return SkipTask(now, task)
+}
+
+func (s *TaskService) MoveTask(now time.Time, task *model.Tasks, dateStr string) (model.TaskException, error) {
+	return MoveTask(now, task, dateStr)
+}
+
+func (s *TaskService) NextDates(now time.Time, dateStr string, repeat string, count int, until time.Time) ([]string, error) {
+	return NextDates(now, dateStr, repeat, count, until)
+}
+
+func (s *TaskService) DescribeRepeat(repeat string, lang string) (*RepeatRule, string, error) {
+	return DescribeRepeat(repeat, lang)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"database/sql"
+	"go_final_project/model"
+	"time"
+)
+
+// deletedTask отбирает задачи, которые лежат в корзине
+const deletedTask = "deleted_at != ''"
+
+// GetTrash возвращает задачи из корзины, которые видит пользователь: сначала удалённые последними
+func (r *TaskRepository) GetTrash(userID int64) ([]model.Tasks, error) {
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE " + deletedTask + " AND " + visibleTask + " ORDER BY deleted_at DESC, id DESC"
+	rows, err := r.DB.Query(query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Tasks
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// GetTrashedTask возвращает задачу из корзины или sql.ErrNoRows, если её там нет
+func (r *TaskRepository) GetTrashedTask(userID int64, id int) (model.Tasks, error) {
+	query := "SELECT " + taskColumns + " FROM scheduler WHERE id = ? AND " + deletedTask + " AND " + visibleTask
+	return scanTask(r.DB.QueryRow(query, id, userID, userID))
+}
+
+// RestoreTask возвращает задачу из корзины туда, откуда она была удалена
+func (r *TaskRepository) RestoreTask(actor Actor, id int) (int64, error) {
+	query := "UPDATE scheduler SET deleted_at = '', version = version + 1 WHERE id = ? AND " + deletedTask + " AND " + editableTask
+	return r.changeTask(actor, AuditActionRestore, id, query, id, actor.UserID, actor.UserID)
+}
+
+// PurgeTask окончательно удаляет задачу из корзины вместе с переносами и историей выполнения.
+// В журнале аудита остаётся последнее состояние задачи.
+func (r *TaskRepository) PurgeTask(actor Actor, id int) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before, err := getTaskTx(tx, id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	query := "DELETE FROM scheduler WHERE id = ? AND " + deletedTask + " AND " + editableTask
+	result, err := tx.Exec(query, id, actor.UserID, actor.UserID)
+	if err != nil {
+		return 0, err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil || affectedRows == 0 {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM task_exceptions WHERE task_id = ?", id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM task_completions WHERE task_id = ?", id); err != nil {
+		return 0, err
+	}
+	if err := writeAudit(tx, actor, AuditActionPurge, &before, nil); err != nil {
+		return 0, err
+	}
+	return affectedRows, tx.Commit()
+}
+
+// TrashRetentionRequestID — X-Request-ID записей журнала о задачах, которые сервер удалил
+// из корзины по сроку хранения. Пользователя у такого удаления нет, поэтому запись делается
+// от имени владельца задачи: так её видят те же, кто видел задачу.
+const TrashRetentionRequestID = "trash-retention"
+
+// PurgeTrash окончательно удаляет задачи всех пользователей, попавшие в корзину раньше deletedBefore,
+// и возвращает их число
+func (r *TaskRepository) PurgeTrash(deletedBefore time.Time) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before := deletedBefore.UTC().Format(time.RFC3339)
+	expired, err := expiredTrash(tx, before)
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range expired {
+		actor := Actor{UserID: task.UserID, RequestID: TrashRetentionRequestID}
+		if err := writeAudit(tx, actor, AuditActionPurge, &task, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	expiredIDs := "SELECT id FROM scheduler WHERE " + deletedTask + " AND deleted_at < ?"
+	for _, table := range []string{"task_exceptions", "task_completions"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE task_id IN ("+expiredIDs+")", before); err != nil {
+			return 0, err
+		}
+	}
+	result, err := tx.Exec("DELETE FROM scheduler WHERE "+deletedTask+" AND deleted_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return purged, tx.Commit()
+}
+
+// expiredTrash читает задачи, которые попали в корзину раньше before, чтобы записать их удаление в журнал
+func expiredTrash(tx *Tx, before string) ([]model.Tasks, error) {
+	rows, err := tx.Query("SELECT "+taskColumns+" FROM scheduler WHERE "+deletedTask+" AND deleted_at < ?", before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Tasks
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go_final_project/model"
+	"time"
+)
+
+const (
+	UndoActionDone   = "done"
+	UndoActionDelete = "delete"
+
+	// UndoTokenHeader — заголовок ответа, в котором /api/task/done и DELETE /api/task возвращают токен отмены
+	UndoTokenHeader = "X-Undo-Token"
+)
+
+// UndoSnapshot — состояние задачи до изменения, по которому /api/undo её восстанавливает
+type UndoSnapshot struct {
+	Task model.Tasks `json:"task"`
+	// Completion — запись истории, добавленная отменяемым выполнением
+	Completion *model.TaskCompletion `json:"completion,omitempty"`
+	// Version — версия задачи после отменяемого действия. Если задачу с тех пор изменили,
+	// отмена не применяется.
+	Version int64 `json:"version"`
+}
+
+func NewUndoToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SaveUndo запоминает снимок задачи до изменения и заодно удаляет просроченные записи.
+// Отменить действие сможет только пользователь userID, который его выполнил.
+func (r *TaskRepository) SaveUndo(userID int64, token string, action string, snapshot UndoSnapshot, expiresAt time.Time) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if _, err := r.DB.Exec("DELETE FROM undo_log WHERE expires_at < ?", time.Now().Unix()); err != nil {
+		return err
+	}
+	query := "INSERT INTO undo_log (token, action, task_id, user_id, snapshot, expires_at) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err = r.DB.Exec(query, token, action, snapshot.Task.ID, userID, string(data), expiresAt.Unix())
+	return err
+}
+
+// Undo в одной транзакции восстанавливает задачу по токену, записывает отмену в журнал аудита
+// и удаляет токен, чтобы отмену нельзя было применить дважды. Если токена нет, он выдан другому
+// пользователю, срок отмены истёк или пользователь больше не может менять задачу, возвращается
+// sql.ErrNoRows. Если задачу изменили после отменяемого действия, возвращается её текущее
+// состояние и ErrVersionConflict.
+func (r *TaskRepository) Undo(actor Actor, token string, now time.Time) (model.Tasks, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return model.Tasks{}, err
+	}
+	defer tx.Rollback()
+
+	var action, data string
+	query := "SELECT action, snapshot FROM undo_log WHERE token = ? AND user_id = ? AND expires_at >= ?"
+	if err := tx.QueryRow(query, token, actor.UserID, now.Unix()).Scan(&action, &data); err != nil {
+		return model.Tasks{}, err
+	}
+	var snapshot UndoSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return model.Tasks{}, err
+	}
+
+	task := snapshot.Task
+	query = "SELECT " + taskColumns + " FROM scheduler WHERE id = ? AND " + editableTask
+	before, err := scanTask(tx.QueryRow(query, task.ID, actor.UserID, actor.UserID))
+	if err != nil {
+		return model.Tasks{}, err
+	}
+	switch action {
+	case UndoActionDone:
+		err = restoreDoneTask(tx, snapshot)
+	case UndoActionDelete:
+		err = restoreDeletedTask(tx, snapshot)
+	default:
+		err = fmt.Errorf("неизвестное действие для отмены: %s", action)
+	}
+	if err == ErrVersionConflict {
+		return before, err
+	} else if err != nil {
+		return model.Tasks{}, err
+	}
+	after, err := getTaskTx(tx, task.ID)
+	if err != nil {
+		return model.Tasks{}, err
+	}
+	if err := writeAudit(tx, actor, AuditActionUndo, &before, &after); err != nil {
+		return model.Tasks{}, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM undo_log WHERE token = ?", token); err != nil {
+		return model.Tasks{}, err
+	}
+	return task, tx.Commit()
+}
+
+// restoreDoneTask возвращает дату, правило и число выполнений задачи из снимка и удаляет
+// запись о выполнении. Если задачу изменили после выполнения, возвращается ErrVersionConflict.
+func restoreDoneTask(tx *Tx, snapshot UndoSnapshot) error {
+	task := snapshot.Task
+	query := `UPDATE scheduler SET date = ?, repeat = ?, done_count = ?, anchor_date = ?, completed_at = ?, version = version + 1
+		WHERE id = ? AND version = ?`
+	result, err := tx.Exec(query, task.Date, task.Repeat, task.DoneCount, task.AnchorDate, task.CompletedAt, task.ID, snapshot.Version)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrVersionConflict
+	}
+
+	if c := snapshot.Completion; c != nil {
+		query = "DELETE FROM task_completions WHERE task_id = ? AND date = ? AND done_at = ?"
+		if _, err := tx.Exec(query, c.TaskID, c.Date, c.DoneAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreDeletedTask достаёт задачу из корзины. Если её уже восстановили или изменили
+// после удаления, возвращается ErrVersionConflict.
+func restoreDeletedTask(tx *Tx, snapshot UndoSnapshot) error {
+	query := "UPDATE scheduler SET deleted_at = '', version = version + 1 WHERE id = ? AND version = ? AND deleted_at != ''"
+	result, err := tx.Exec(query, snapshot.Task.ID, snapshot.Version)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
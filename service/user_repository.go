@@ -0,0 +1,47 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"go_final_project/model"
+	"time"
+)
+
+// ErrLoginTaken возвращается при регистрации с уже занятым логином
+var ErrLoginTaken = errors.New("логин уже занят")
+
+const userColumns = "id, login, password_hash, created_at"
+
+type UserRepository struct {
+	DB *DB
+}
+
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{DB: db}
+}
+
+func scanUser(row rowScanner) (model.User, error) {
+	var user model.User
+	err := row.Scan(&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt)
+	return user, err
+}
+
+func (r *UserRepository) CreateUser(login string, passwordHash string) (int64, error) {
+	if _, err := r.GetUserByLogin(login); err == nil {
+		return 0, ErrLoginTaken
+	} else if err != sql.ErrNoRows {
+		return 0, err
+	}
+	query := "INSERT INTO users (login, password_hash, created_at) VALUES (?, ?, ?) RETURNING id"
+	var id int64
+	err := r.DB.QueryRow(query, login, passwordHash, time.Now().UTC().Format(time.RFC3339)).Scan(&id)
+	return id, err
+}
+
+func (r *UserRepository) GetUserByLogin(login string) (model.User, error) {
+	return scanUser(r.DB.QueryRow("SELECT "+userColumns+" FROM users WHERE login = ?", login))
+}
+
+func (r *UserRepository) GetUserByID(id int64) (model.User, error) {
+	return scanUser(r.DB.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"go_final_project/model"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength — bcrypt учитывает только первые 72 байта пароля
+	MaxPasswordLength = 72
+)
+
+var loginPattern = regexp.MustCompile(`^[a-z0-9._-]{3,64}$`)
+
+// dummyPasswordHash сравнивается с паролем, когда логин не найден, чтобы по времени ответа
+// нельзя было узнать, какие логины существуют
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// ValidateCredentials приводит логин к нижнему регистру и проверяет логин и пароль перед регистрацией
+func ValidateCredentials(credentials *model.Credentials) error {
+	credentials.Login = strings.ToLower(strings.TrimSpace(credentials.Login))
+	if !loginPattern.MatchString(credentials.Login) {
+		return &ValidationError{Field: "login", Message: "Логин должен содержать от 3 до 64 латинских букв, цифр или символов . _ -"}
+	}
+	if len(credentials.Password) < MinPasswordLength {
+		return &ValidationError{Field: "password", Message: "Пароль должен быть не короче 8 символов"}
+	}
+	if len(credentials.Password) > MaxPasswordLength {
+		return &ValidationError{Field: "password", Message: "Пароль должен быть не длиннее 72 байт"}
+	}
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword сверяет пароль с хешем пользователя; user == nil означает, что логин не найден
+func CheckPassword(user *model.User, password string) bool {
+	if user == nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
@@ -1,19 +1,113 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"go_final_project/model"
 	"time"
 )
 
+// ValidationError — ошибка в конкретном поле задачи
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidateTask проверяет задачу перед сохранением: подставляет итоговую дату
+// и приводит правило повторения к канонической записи
+func ValidateTask(now time.Time, task *model.Tasks) error {
+	if task.Title == "" {
+		return &ValidationError{Field: "title", Message: "Не указан заголовок задачи"}
+	}
+	if err := validateSchedule(now, task); err != nil {
+		return err
+	}
+	return validateRepeatLimits(task)
+}
+
+// ValidateTaskPatch проверяет задачу stored после частичного изменения. Дата и правило
+// повторения проверяются и пересчитываются, только если изменились: иначе просроченная
+// задача при правке комментария переехала бы на сегодня.
+func ValidateTaskPatch(now time.Time, stored model.Tasks, task *model.Tasks) error {
+	if task.Title == "" {
+		return &ValidationError{Field: "title", Message: "Не указан заголовок задачи"}
+	}
+	scheduleChanged := task.Date != stored.Date || task.Repeat != stored.Repeat
+	if scheduleChanged {
+		if err := validateSchedule(now, task); err != nil {
+			return err
+		}
+	}
+	if scheduleChanged || task.EndDate != stored.EndDate || task.MaxCount != stored.MaxCount {
+		return validateRepeatLimits(task)
+	}
+	return nil
+}
+
+// validateSchedule приводит правило повторения к канонической записи и подставляет итоговую дату
+func validateSchedule(now time.Time, task *model.Tasks) error {
+	if task.Repeat != "" {
+		rule, err := ParseRepeatRule(task.Repeat)
+		if err != nil {
+			return &ValidationError{Field: "repeat", Message: fmt.Sprintf("ошибка в правиле повторения: %v", err)}
+		}
+		task.Repeat = rule.String()
+	}
+
+	date, err := ValidateTaskDate(now, task.Date, task.Repeat)
+	if err != nil {
+		return err
+	}
+	task.Date = date
+	return nil
+}
+
+// validateRepeatLimits проверяет ограничения повторений: дату окончания и число выполнений
+func validateRepeatLimits(task *model.Tasks) error {
+	if task.MaxCount < 0 {
+		return &ValidationError{Field: "max_count", Message: "Число выполнений не может быть отрицательным"}
+	}
+	if task.Repeat == "" {
+		if task.EndDate != "" {
+			return &ValidationError{Field: "end_date", Message: "Дата окончания задаётся только для повторяющейся задачи"}
+		}
+		if task.MaxCount > 0 {
+			return &ValidationError{Field: "max_count", Message: "Число выполнений задаётся только для повторяющейся задачи"}
+		}
+		return nil
+	}
+	if task.EndDate == "" {
+		return nil
+	}
+	if _, err := time.Parse(DateFormat, task.EndDate); err != nil {
+		return &ValidationError{Field: "end_date", Message: fmt.Sprintf("некорректная дата окончания. Ожидается формат 20060102: %v", err)}
+	}
+	if task.EndDate < task.Date {
+		return &ValidationError{Field: "end_date", Message: "Дата окончания повторений раньше даты задачи"}
+	}
+	return nil
+}
+
 // ValidateTaskDate проверяет дату задачи и возвращает её
 func ValidateTaskDate(now time.Time, taskDateStr string, repeat string) (string, error) {
 	if taskDateStr == "" {
+		if err := validateRepeat(now, now.Format(DateFormat), repeat); err != nil {
+			return "", err
+		}
 		return now.Format(DateFormat), nil
 	}
 
 	taskDate, err := time.Parse(DateFormat, taskDateStr)
 	if err != nil {
-		return "", fmt.Errorf("некорректная дата. Ожидается формат 20060102: %v", err)
+		return "", &ValidationError{Field: "date", Message: fmt.Sprintf("некорректная дата. Ожидается формат 20060102: %v", err)}
+	}
+
+	if err := validateRepeat(now, taskDateStr, repeat); err != nil {
+		return "", err
 	}
 
 	parsedDate := taskDate.Truncate(24 * time.Hour)
@@ -25,10 +119,21 @@ func ValidateTaskDate(now time.Time, taskDateStr string, repeat string) (string,
 		}
 		nextDate, err := NextDate(now, taskDateStr, repeat)
 		if err != nil {
-			return "", fmt.Errorf("ошибка в правиле повторения: %v", err)
+			return "", &ValidationError{Field: "repeat", Message: fmt.Sprintf("ошибка в правиле повторения: %v", err)}
 		}
 		return nextDate, nil
 	}
 
 	return taskDateStr, nil
 }
+
+// validateRepeat проверяет правило повторения тем же кодом, которым рассчитывается следующая дата
+func validateRepeat(now time.Time, taskDateStr string, repeat string) error {
+	if repeat == "" {
+		return nil
+	}
+	if _, err := NextDate(now, taskDateStr, repeat); err != nil && !errors.Is(err, ErrRepeatFinished) {
+		return &ValidationError{Field: "repeat", Message: fmt.Sprintf("ошибка в правиле повторения: %v", err)}
+	}
+	return nil
+}
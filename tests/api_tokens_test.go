@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestBearer выполняет запрос с личным токеном в заголовке Authorization
+func requestBearer(t *testing.T, token string, apipath string, values map[string]any, method string) (int, map[string]any) {
+	code, _, body := doRequest(t, method, apipath, values, withHeader("Authorization", "Bearer "+token))
+	return code, decodeBody(t, body)
+}
+
+func createAPIToken(t *testing.T, session string, name string, scope string) (string, string) {
+	code, ret := requestAs(t, session, "api/tokens", map[string]any{"name": name, "scope": scope}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, ret)
+	token, _ := ret["token"].(string)
+	assert.NotEmpty(t, token)
+	return fmt.Sprint(ret["id"]), token
+}
+
+func TestAPITokens(t *testing.T) {
+	session := registerUser(t, "tokens-"+time.Now().Format("150405.000000"))
+
+	code, ret := requestAs(t, session, "api/tokens", map[string]any{"name": ""}, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "name", ret["field"])
+	code, ret = requestAs(t, session, "api/tokens", map[string]any{"name": "CI", "scope": "admin"}, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "scope", ret["field"])
+
+	writeID, write := createAPIToken(t, session, "Скрипт", "")
+	_, read := createAPIToken(t, session, "Дашборд", "read")
+
+	code, ret = requestBearer(t, write, "api/task", map[string]any{
+		"date":  time.Now().Format(`20060102`),
+		"title": "Задача по токену",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, ret)
+	id := fmt.Sprint(ret["id"])
+
+	code, ret = requestBearer(t, read, "api/tasks", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, ret["tasks"], 1)
+	code, ret = requestBearer(t, read, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Задача по токену", ret["title"])
+
+	code, _ = requestBearer(t, read, "api/task", map[string]any{
+		"date":  time.Now().Format(`20060102`),
+		"title": "Запрещено",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = requestBearer(t, read, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = requestBearer(t, read, "api/task?id="+id, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = requestBearer(t, write, "api/tokens", map[string]any{"name": "Ещё один"}, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code, "Токен не должен выпускать новые токены")
+
+	code, ret = requestAs(t, session, "api/tokens", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	tokens, _ := ret["tokens"].([]any)
+	if assert.Len(t, tokens, 2) {
+		for _, v := range tokens {
+			token := v.(map[string]any)
+			assert.Empty(t, token["token"], "Токен показывается только при создании")
+			assert.NotEmpty(t, token["last_used_at"])
+		}
+	}
+
+	code, _ = requestAs(t, registerUser(t, "stranger-"+time.Now().Format("150405.000000")), "api/tokens?id="+writeID, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = requestAs(t, session, "api/tokens?id="+writeID, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = requestBearer(t, write, "api/task?id="+id, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = requestBearer(t, "todo_unknown", "api/tasks", nil, http.MethodGet)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = requestAs(t, session, "api/task?id="+id, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+}
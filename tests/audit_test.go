@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type auditEntry struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"user_id"`
+	Action    string         `json:"action"`
+	TaskID    string         `json:"task_id"`
+	Before    map[string]any `json:"before"`
+	After     map[string]any `json:"after"`
+	RequestID string         `json:"request_id"`
+	CreatedAt string         `json:"created_at"`
+}
+
+type auditPage struct {
+	Entries    []auditEntry `json:"entries"`
+	NextBefore string       `json:"next_before"`
+	Error      string       `json:"error"`
+}
+
+func getAudit(t *testing.T, apipath string) auditPage {
+	body, err := requestJSON(apipath, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var page auditPage
+	assert.NoError(t, json.Unmarshal(body, &page), string(body))
+	return page
+}
+
+// putWithRequestID изменяет задачу, передавая свой X-Request-ID, и возвращает X-Request-ID из ответа
+func putWithRequestID(t *testing.T, requestID string, values map[string]any) string {
+	code, header, _ := doRequest(t, http.MethodPut, "api/task", values, withCookie(Token), withHeader("X-Request-ID", requestID))
+	assert.Equal(t, http.StatusOK, code)
+	return header.Get("X-Request-ID")
+}
+
+func TestAudit(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	id := addTask(t, task{date: today, title: "Позвонить в банк"})
+	requestID := "audit-" + time.Now().Format("150405.000000")
+	echoed := putWithRequestID(t, requestID, map[string]any{
+		"id": id, "date": today, "title": "Позвонить в страховую", "version": "1",
+	})
+	assert.Equal(t, requestID, echoed)
+	ret, err := postJSON(donePath(t, db, id), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	page := getAudit(t, "api/task/audit?id="+id)
+	if assert.Len(t, page.Entries, 3) {
+		done, update, create := page.Entries[0], page.Entries[1], page.Entries[2]
+		assert.Equal(t, []string{"done", "update", "create"}, []string{done.Action, update.Action, create.Action})
+		for _, entry := range page.Entries {
+			assert.Equal(t, id, entry.TaskID)
+			assert.NotEmpty(t, entry.CreatedAt)
+			assert.NotEmpty(t, entry.RequestID)
+		}
+
+		assert.Nil(t, create.Before)
+		assert.Equal(t, "Позвонить в банк", create.After["title"])
+		assert.Equal(t, "Позвонить в банк", update.Before["title"])
+		assert.Equal(t, "Позвонить в страховую", update.After["title"])
+		assert.Equal(t, requestID, update.RequestID)
+		assert.Empty(t, done.Before["completed_at"])
+		assert.NotEmpty(t, done.After["completed_at"])
+	}
+
+	other := addTask(t, task{date: today, title: "Временная задача"})
+	ret, err = postJSON("api/task?id="+other, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	page = getAudit(t, "api/audit?task="+other+"&action=delete")
+	if assert.Len(t, page.Entries, 1) {
+		assert.Empty(t, page.Entries[0].Before["deleted_at"])
+		assert.NotEmpty(t, page.Entries[0].After["deleted_at"])
+	}
+	page = getAudit(t, "api/task/audit?id="+other)
+	assert.Len(t, page.Entries, 2, "Журнал удалённой задачи должен быть доступен")
+
+	page = getAudit(t, "api/audit?limit=2&from="+time.Now().UTC().Format(`20060102`))
+	assert.Len(t, page.Entries, 2)
+	assert.NotEmpty(t, page.NextBefore)
+	next := getAudit(t, "api/audit?limit=2&before="+page.NextBefore)
+	if assert.NotEmpty(t, next.Entries) {
+		assert.NotEqual(t, page.Entries[1].ID, next.Entries[0].ID)
+	}
+	page = getAudit(t, "api/audit?task="+id+"&to=20000101")
+	assert.Empty(t, page.Entries)
+
+	for _, apipath := range []string{
+		"api/audit?action=unknown",
+		"api/audit?from=2024-01-01",
+		"api/audit?from=20240201&to=20240101",
+		"api/audit?task=abc",
+		"api/audit?limit=0",
+		"api/task/audit",
+		"api/task/audit?id=999999999",
+	} {
+		assert.NotEmpty(t, getAudit(t, apipath).Error, apipath)
+	}
+
+	stranger := registerUser(t, "auditor-"+time.Now().Format("150405.000000"))
+	code, _ := requestAs(t, stranger, "api/task/audit?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code, "Чужой журнал не должен быть виден")
+	code, ret = requestAs(t, stranger, "api/audit?task="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, ret["entries"])
+
+	_, err = db.Exec(db.Rebind(`DELETE FROM audit_log WHERE task_id = ?`), id)
+	assert.Error(t, err, "Записи журнала нельзя удалять")
+	_, err = db.Exec(db.Rebind(`UPDATE audit_log SET action = 'create' WHERE task_id = ?`), id)
+	assert.Error(t, err, "Записи журнала нельзя менять")
+}
+
+func TestAuditTaskLifecycle(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+	id := addTask(t, task{date: day(0), title: "Проветрить комнату", repeat: "d 7"})
+	for _, path := range []string{"api/task/skip?id=" + id, "api/task/move?id=" + id + "&date=" + day(8)} {
+		ret, err := postJSON(path, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret, path)
+	}
+	token := requestUndoToken(t, "api/task?id="+id, http.MethodDelete)
+	assert.Equal(t, id, undo(t, token)["id"])
+	for _, step := range []struct{ path, method string }{
+		{"api/task?id=" + id, http.MethodDelete},
+		{"api/trash/restore?id=" + id, http.MethodPost},
+		{"api/task?id=" + id, http.MethodDelete},
+		{"api/trash?id=" + id, http.MethodDelete},
+	} {
+		ret, err := postJSON(step.path, nil, step.method)
+		assert.NoError(t, err)
+		assert.Empty(t, ret, step.path)
+	}
+
+	page := getAudit(t, "api/audit?task="+id)
+	actions := make([]string, len(page.Entries))
+	for i, entry := range page.Entries {
+		actions[i] = entry.Action
+	}
+	assert.Equal(t, []string{"purge", "delete", "restore", "delete", "undo", "delete", "move", "skip", "create"}, actions)
+	if assert.Len(t, page.Entries, 9) {
+		purge, undone, move := page.Entries[0], page.Entries[4], page.Entries[6]
+		assert.Equal(t, "Проветрить комнату", purge.Before["title"])
+		assert.Nil(t, purge.After)
+		assert.NotEmpty(t, undone.Before["deleted_at"])
+		assert.Empty(t, undone.After["deleted_at"])
+		assert.Equal(t, day(7), move.Before["date"])
+		assert.Equal(t, day(8), move.After["date"])
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date": day(0), "title": "Последний полив", "repeat": "d 7", "end_date": day(3),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	last := fmt.Sprint(ret["id"])
+	ret, err = postJSON("api/task/skip?id="+last, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	page = getAudit(t, "api/audit?task="+last+"&action=archive")
+	if assert.Len(t, page.Entries, 1) {
+		assert.NotEmpty(t, page.Entries[0].After["completed_at"])
+	}
+}
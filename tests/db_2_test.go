@@ -6,16 +6,27 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID          int64  `db:"id"`
+	Date        string `db:"date"`
+	Title       string `db:"title"`
+	Comment     string `db:"comment"`
+	Repeat      string `db:"repeat"`
+	EndDate     string `db:"end_date"`
+	MaxCount    int    `db:"max_count"`
+	DoneCount   int    `db:"done_count"`
+	AnchorDate  string `db:"anchor_date"`
+	CompletedAt string `db:"completed_at"`
+	CreatedAt   string `db:"created_at"`
+	DeletedAt   string `db:"deleted_at"`
+	Version     int64  `db:"version"`
+	UserID      int64  `db:"user_id"`
+	ListID      int64  `db:"list_id"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -23,7 +34,20 @@ func count(db *sqlx.DB) (int, error) {
 	return count, db.Get(&count, `SELECT count(id) FROM scheduler`)
 }
 
+// openDB подключается к той же базе, что и сервер: к PostgreSQL, если задана DatabaseURL
+// или TODO_DATABASE_URL, иначе к файлу SQLite. Запросы с плейсхолдерами ? нужно
+// пропускать через db.Rebind.
 func openDB(t *testing.T) *sqlx.DB {
+	url := DatabaseURL
+	if envURL := os.Getenv("TODO_DATABASE_URL"); len(envURL) > 0 {
+		url = envURL
+	}
+	if len(url) > 0 {
+		db, err := sqlx.Connect("postgres", url)
+		assert.NoError(t, err)
+		return db
+	}
+
 	dbfile := DBFile
 	envFile := os.Getenv("TODO_DBFILE")
 	if len(envFile) > 0 {
@@ -43,20 +67,19 @@ func TestDB(t *testing.T) {
 
 	today := time.Now().Format(`20060102`)
 
-	res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) 
-	VALUES (?, 'Todo', 'Комментарий', '')`, today)
+	var id int64
+	err = db.Get(&id, db.Rebind(`INSERT INTO scheduler (date, title, comment, repeat) 
+	VALUES (?, 'Todo', 'Комментарий', '') RETURNING id`), today)
 	assert.NoError(t, err)
 
-	id, err := res.LastInsertId()
-
 	var task Task
-	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
 	assert.NoError(t, err)
 	assert.Equal(t, id, task.ID)
 	assert.Equal(t, `Todo`, task.Title)
 	assert.Equal(t, `Комментарий`, task.Comment)
 
-	_, err = db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	_, err = db.Exec(db.Rebind(`DELETE FROM scheduler WHERE id = ?`), id)
 	assert.NoError(t, err)
 
 	after, err := count(db)
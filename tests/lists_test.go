@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLists(t *testing.T) {
+	suffix := time.Now().Format("150405.000000")
+	owner := registerUser(t, "owner-"+suffix)
+	viewer := registerUser(t, "viewer-"+suffix)
+	editor := registerUser(t, "editor-"+suffix)
+	stranger := registerUser(t, "stranger-"+suffix)
+
+	code, ret := requestAs(t, owner, "api/lists", map[string]any{"name": "Ремонт"}, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, ret)
+	assert.Equal(t, "owner", ret["role"])
+	list := fmt.Sprint(ret["id"])
+
+	invite := func(token string, login string, role string) int {
+		code, _ := requestAs(t, token, "api/lists/members", map[string]any{"list_id": list, "login": login, "role": role}, http.MethodPost)
+		return code
+	}
+	assert.Equal(t, http.StatusOK, invite(owner, "viewer-"+suffix, "viewer"))
+	assert.Equal(t, http.StatusOK, invite(owner, "editor-"+suffix, "editor"))
+	assert.Equal(t, http.StatusNotFound, invite(owner, "nobody-"+suffix, "viewer"))
+	assert.Equal(t, http.StatusBadRequest, invite(owner, "stranger-"+suffix, "admin"))
+	assert.Equal(t, http.StatusForbidden, invite(editor, "stranger-"+suffix, "viewer"))
+	assert.Equal(t, http.StatusNotFound, invite(stranger, "stranger-"+suffix, "owner"))
+
+	code, ret = requestAs(t, viewer, "api/lists", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	if lists, _ := ret["lists"].([]any); assert.Len(t, lists, 1) {
+		assert.Equal(t, "viewer", lists[0].(map[string]any)["role"])
+	}
+	code, ret = requestAs(t, viewer, "api/lists/members?list="+list, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, ret["members"], 3)
+
+	now := time.Now().Format(`20060102`)
+	code, _ = requestAs(t, viewer, "api/task", map[string]any{"date": now, "title": "Купить краску", "list_id": list}, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, ret = requestAs(t, editor, "api/task", map[string]any{"date": now, "title": "Купить краску", "list_id": list}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, ret)
+	id := fmt.Sprint(ret["id"])
+
+	code, ret = requestAs(t, viewer, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, list, ret["list_id"])
+	_, ret = requestAs(t, viewer, "api/tasks?list="+list, nil, http.MethodGet)
+	assert.Len(t, ret["tasks"], 1)
+	_, ret = requestAs(t, editor, "api/tasks", nil, http.MethodGet)
+	assert.Empty(t, ret["tasks"], "Задачи списка не попадают в личные задачи")
+
+	code, _ = requestAs(t, viewer, "api/task", map[string]any{"id": id, "date": now, "title": "Изменено"}, http.MethodPut)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = requestAs(t, viewer, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = requestAs(t, viewer, "api/task?id="+id, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = requestAs(t, stranger, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = requestAs(t, stranger, "api/tasks?list="+list, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	_, ret = requestAs(t, owner, "api/task?id="+id, nil, http.MethodGet)
+	code, _ = requestAs(t, owner, "api/task", map[string]any{"id": id, "date": now, "title": "Купить белую краску", "version": ret["version"]}, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code)
+	_, ret = requestAs(t, editor, "api/task?id="+id, nil, http.MethodGet)
+	code, _ = requestAs(t, editor, "api/task/done?id="+id+"&version="+fmt.Sprint(ret["version"]), nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = requestAs(t, viewer, "api/task/history?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+
+	_, ret = requestAs(t, viewer, "api/lists/members?list="+list, nil, http.MethodGet)
+	var viewerID, ownerID string
+	for _, v := range ret["members"].([]any) {
+		member := v.(map[string]any)
+		switch member["role"] {
+		case "viewer":
+			viewerID = fmt.Sprint(member["user_id"])
+		case "owner":
+			ownerID = fmt.Sprint(member["user_id"])
+		}
+	}
+	code, _ = requestAs(t, owner, "api/lists/members?list="+list+"&user="+ownerID, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusConflict, code, "Последний владелец не может покинуть список")
+	code, _ = requestAs(t, editor, "api/lists/members?list="+list+"&user="+viewerID, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = requestAs(t, owner, "api/lists/members?list="+list+"&user="+viewerID, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = requestAs(t, viewer, "api/tasks?list="+list, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = requestAs(t, "", "api/lists", nil, http.MethodGet)
+	if len(Token) == 0 {
+		assert.Equal(t, http.StatusForbidden, code)
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"go_final_project/migrations"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	all, err := migrations.Load(db.DriverName())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, all)
+
+	var versions []int
+	err = db.Select(&versions, "SELECT version FROM schema_migrations ORDER BY version")
+	assert.NoError(t, err)
+	assert.Len(t, versions, len(all), "Сервер должен применить все миграции при запуске")
+	for i, version := range versions {
+		assert.Equal(t, i+1, version)
+	}
+
+	status, err := migrations.GetStatus(db.DB, db.DriverName())
+	assert.NoError(t, err)
+	assert.Empty(t, status.Pending)
+	assert.Equal(t, migrations.Latest(db.DriverName()), status.Applied[len(status.Applied)-1].Version)
+}
+
+// openTempDB открывает новую базу SQLite во временном каталоге теста
+func openTempDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", filepath.Join(t.TempDir(), "scheduler.db"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return db
+}
+
+func TestMigrationsLegacyDatabase(t *testing.T) {
+	db := openTempDB(t)
+	defer db.Close()
+
+	// scheduler.db до перехода на миграции: таблица без новых столбцов и триггер индекса FTS5,
+	// который сборка без тега sqlite_fts5 выполнить не может
+	_, err := db.Exec(`
+		CREATE TABLE scheduler (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			date CHAR(8) NOT NULL DEFAULT "",
+			title VARCHAR(256) NOT NULL DEFAULT "",
+			comment TEXT,
+			repeat VARCHAR(128) NOT NULL DEFAULT ""
+		);
+		CREATE INDEX scheduler_date ON scheduler (date);
+		INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240126', 'Купить молоко', '', 'd 1');
+		CREATE TRIGGER scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+			INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+		END;
+	`)
+	assert.NoError(t, err)
+
+	applied, err := migrations.Apply(db.DB, "sqlite3")
+	assert.NoError(t, err)
+	assert.Len(t, applied, migrations.Latest("sqlite3"))
+
+	var task struct {
+		Title     string `db:"title"`
+		EndDate   string `db:"end_date"`
+		DoneCount int    `db:"done_count"`
+		ListID    int64  `db:"list_id"`
+		DeletedAt string `db:"deleted_at"`
+		Version   int64  `db:"version"`
+	}
+	err = db.Get(&task, "SELECT title, end_date, done_count, list_id, deleted_at, version FROM scheduler WHERE id = 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Купить молоко", task.Title, "Задачи из старой базы должны сохраниться")
+	assert.Empty(t, task.EndDate)
+	assert.EqualValues(t, 1, task.Version)
+
+	_, err = db.Exec("INSERT INTO scheduler (date, title, comment) VALUES ('20240127', 'Вернуть молоко', '')")
+	assert.NoError(t, err, "Триггеры прежнего индекса FTS5 должны быть удалены")
+	var found int
+	err = db.Get(&found, "SELECT COUNT(*) FROM scheduler_search WHERE scheduler_search MATCH 'молок*'")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, found, "Индекс должен содержать и старые, и новые задачи")
+
+	applied, err = migrations.Apply(db.DB, "sqlite3")
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestMigrationsDatabaseNewer(t *testing.T) {
+	db := openTempDB(t)
+	defer db.Close()
+
+	_, err := migrations.Apply(db.DB, "sqlite3")
+	assert.NoError(t, err)
+	newer := migrations.Latest("sqlite3") + 1
+	_, err = db.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", newer, "2030-01-01T00:00:00Z")
+	assert.NoError(t, err)
+
+	applied, err := migrations.Apply(db.DB, "sqlite3")
+	assert.ErrorIs(t, err, migrations.ErrDatabaseNewer, "Приложение не должно работать с базой новее себя")
+	assert.Empty(t, applied)
+	status, err := migrations.GetStatus(db.DB, "sqlite3")
+	assert.ErrorIs(t, err, migrations.ErrDatabaseNewer)
+	if assert.NotEmpty(t, status.Applied) {
+		last := status.Applied[len(status.Applied)-1]
+		assert.Equal(t, newer, last.Version)
+		assert.Empty(t, last.Name, "Версия неизвестна приложению")
+	}
+	assert.Empty(t, status.Pending)
+}
@@ -37,22 +37,7 @@ func TestNextDate(t *testing.T) {
 		{"20231225", "d 12", `20240130`},
 		{"20240228", "d 1", "20240229"},
 	}
-	check := func() {
-		for _, v := range tbl {
-			urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
-				url.QueryEscape(v.date), url.QueryEscape(v.repeat))
-			get, err := getBody(urlPath)
-			assert.NoError(t, err)
-			next := strings.TrimSpace(string(get))
-			_, err = time.Parse("20060102", next)
-			if err != nil && len(v.want) == 0 {
-				continue
-			}
-			assert.Equal(t, v.want, next, `{%q, %q, %q}`,
-				v.date, v.repeat, v.want)
-		}
-	}
-	check()
+	checkNextDate(t, tbl)
 	if !FullNextDate {
 		return
 	}
@@ -75,5 +60,21 @@ func TestNextDate(t *testing.T) {
 		{"20230126", "w 4,5", "20240201"},
 		{"20230226", "w 8,4,5", ""},
 	}
-	check()
+	checkNextDate(t, tbl)
+}
+
+func checkNextDate(t *testing.T, tbl []nextDate) {
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
 }
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type preview struct {
+	date   string
+	repeat string
+	count  string
+	until  string
+	want   []string
+}
+
+func TestNextDatePreview(t *testing.T) {
+	tbl := []preview{
+		{"20240101", "d 1", "3", "", []string{"20240126", "20240127", "20240128"}},
+		{"20240101", "m -1", "", "20240501", []string{"20240131", "20240229", "20240331", "20240430"}},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=28", "", "", []string{"20240127", "20240128"}},
+		{"20240101", "w 1", "2", "", []string{"20240129", "20240205"}},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate/preview?now=20240126&date=%s&repeat=%s&count=%s&until=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat), v.count, v.until)
+		body, err := getBody(urlPath)
+		assert.NoError(t, err)
+
+		var m map[string][]string
+		err = json.Unmarshal(body, &m)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, m["dates"], `{%q, %q}`, v.date, v.repeat)
+	}
+
+	body, err := getBody("api/nextdate/preview?now=20240126&date=20240101&repeat=y&count=1000")
+	assert.NoError(t, err)
+	var m map[string][]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Len(t, m["dates"], 100)
+
+	body, err = getBody("api/nextdate/preview?now=20240126&date=20240101&repeat=ooops")
+	assert.NoError(t, err)
+	var e map[string]any
+	assert.NoError(t, json.Unmarshal(body, &e))
+	assert.NotEmpty(t, e["error"])
+}
@@ -0,0 +1,21 @@
+package tests
+
+import "testing"
+
+func TestNextDateRRule(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240101", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "20240129"},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=50", "20240127"},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=5", ""},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240120", ""},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "20240229"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", "20240131"},
+		{"20240101", "RRULE:FREQ=YEARLY", "20250101"},
+		{"20240101", "RRULE:FREQ=WEEKLY;BYDAY=2TU", ""},
+		{"20240101", "RRULE:FREQ=HOURLY", ""},
+		{"20240101", "RRULE:INTERVAL=2", ""},
+	}
+	checkNextDate(t, tbl)
+}
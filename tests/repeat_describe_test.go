@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type description struct {
+	repeat string
+	lang   string
+	want   string
+}
+
+func TestDescribeRepeat(t *testing.T) {
+	tbl := []description{
+		{"m 1 1,2", "ru", "каждый 1-й день января и февраля"},
+		{"m 1 1,2", "en", "every 1st day of January and February"},
+		{"m -1,18", "ru", "каждый 18-й и последний день месяца"},
+		{"d 1", "ru", "каждый день"},
+		{"d 3", "ru", "каждые 3 дня"},
+		{"d 21", "en", "every 21 days"},
+		{"y", "ru", "каждый год"},
+		{"w 1,3,5", "ru", "каждую неделю по понедельникам, средам и пятницам"},
+		{"w 7", "en", "every week on Sunday"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=2TU,-1FR", "ru", "каждый месяц во 2-й вторник и в последнюю пятницу"},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=5", "en", "every 2 weeks on Monday and Wednesday, 5 times"},
+		{"RRULE:FREQ=DAILY;UNTIL=20270101", "ru", "каждый день, до 01.01.2027"},
+		{"m -2,-3", "ru", ""},
+		{"y", "fr", ""},
+	}
+	for _, v := range tbl {
+		body, err := getBody(fmt.Sprintf("api/repeat/describe?repeat=%s&lang=%s",
+			url.QueryEscape(v.repeat), v.lang))
+		assert.NoError(t, err)
+
+		var m map[string]any
+		err = json.Unmarshal(body, &m)
+		assert.NoError(t, err)
+		if len(v.want) == 0 {
+			assert.NotEmpty(t, m["error"], "Ожидается ошибка для %q", v.repeat)
+			continue
+		}
+		assert.Equal(t, v.want, m["description"], `{%q, %q}`, v.repeat, v.lang)
+		assert.NotNil(t, m["rule"])
+	}
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatRuleCanonical(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now().Format(`20060102`)
+	tbl := []struct {
+		repeat string
+		want   string
+	}{
+		{"w 5,1,3,1", "w 1,3,5"},
+		{"m 17,10 12,8,1", "m 10,17 1,8,12"},
+		{"m -1,18,-2", "m 18,-2,-1"},
+		{"RRULE:freq=weekly;byday=fr,mo;interval=1", "RRULE:FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"d 7", "d 7"},
+	}
+	for _, v := range tbl {
+		id := addTask(t, task{date: now, title: "Каноническое правило", repeat: v.repeat})
+
+		var task Task
+		err := db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, task.Repeat, "правило %q", v.repeat)
+	}
+}
+
+func TestRepeatRuleFieldErrors(t *testing.T) {
+	now := time.Now().Format(`20060102`)
+	tbl := []struct {
+		task
+		field string
+	}{
+		{task{now, "", "", ""}, "title"},
+		{task{"28.01.2024", "Заголовок", "", ""}, "date"},
+		{task{now, "Заголовок", "", "w 8"}, "repeat"},
+		{task{now, "Заголовок", "", "m 40"}, "repeat"},
+		{task{"", "Заголовок", "", "ooops"}, "repeat"},
+	}
+	for _, v := range tbl {
+		m, err := postJSON("api/task", map[string]any{
+			"date":    v.date,
+			"title":   v.title,
+			"comment": v.comment,
+			"repeat":  v.repeat,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, fmt.Sprint(m["error"]), "Ожидается ошибка для задачи %v", v.task)
+		assert.Equal(t, v.field, m["field"], "Ожидается ошибка в поле %s для задачи %v", v.field, v.task)
+	}
+}
@@ -2,6 +2,12 @@ package tests
 
 var Port = 7540
 var DBFile = "../scheduler.db"
-var FullNextDate = false
-var Search = false
+
+// DatabaseURL — строка подключения к PostgreSQL; если задана, тесты работают с ней, а не с DBFile
+var DatabaseURL = ``
+var FullNextDate = true
+var Search = true
 var Token = ``
+
+// Password — значение TODO_PASSWORD, с которым запущен сервер; пусто, если вход не нужен
+var Password = ``
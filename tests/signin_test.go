@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSignin проверяет вход, если сервер запущен с TODO_PASSWORD и пароль указан в Password
+func TestSignin(t *testing.T) {
+	if len(Password) == 0 {
+		return
+	}
+
+	ret, err := postJSON("api/signin", map[string]any{"password": Password + "x"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	assert.Empty(t, ret["token"])
+
+	ret, err = postJSON("api/signin", map[string]any{"password": Password}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.NotEmpty(t, ret["token"])
+
+	req, err := http.NewRequest(http.MethodGet, getURL("api/tasks"), nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "token", Value: "broken"})
+	resp, err := http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, getURL("api/tasks"), nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "token", Value: ret["token"].(string)})
+	resp, err = http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
@@ -96,6 +96,7 @@ func TestEditTask(t *testing.T) {
 	}
 
 	updateTask := func(newVals map[string]any) {
+		newVals["version"] = strconv.FormatInt(getTask(t, db, id).Version, 10)
 		mupd, err := postJSON("api/task", newVals, http.MethodPut)
 		assert.NoError(t, err)
 
@@ -103,7 +104,7 @@ func TestEditTask(t *testing.T) {
 		assert.False(t, ok && fmt.Sprint(e) != "")
 
 		var task Task
-		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
 		assert.NoError(t, err)
 
 		assert.Equal(t, id, strconv.FormatInt(task.ID, 10))
@@ -29,7 +29,7 @@ func TestDone(t *testing.T) {
 		title: "Свести баланс",
 	})
 
-	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	ret, err := postJSON(donePath(t, db, id), nil, http.MethodPost)
 	assert.NoError(t, err)
 	assert.Empty(t, ret)
 	notFoundTask(t, id)
@@ -41,12 +41,12 @@ func TestDone(t *testing.T) {
 
 	for i := 0; i < 3; i++ {
 
-		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		ret, err := postJSON(donePath(t, db, id), nil, http.MethodPost)
 		assert.NoError(t, err)
 		assert.Empty(t, ret)
 
 		var task Task
-		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
 		assert.NoError(t, err)
 		now = now.AddDate(0, 0, 3)
 		assert.Equal(t, task.Date, now.Format(`20060102`))
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type history struct {
+	Task        map[string]any      `json:"task"`
+	Completions []map[string]string `json:"completions"`
+}
+
+func getHistory(t *testing.T, id string) history {
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var h history
+	err = json.Unmarshal(body, &h)
+	assert.NoError(t, err)
+	return h
+}
+
+// taskIDs возвращает идентификаторы задач, которые выводит /api/tasks
+func taskIDs(t *testing.T) []string {
+	body, err := requestJSON("api/tasks", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Tasks []struct {
+			ID string `json:"id"`
+		} `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	ids := make([]string, len(m.Tasks))
+	for i, task := range m.Tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+func TestTaskHistory(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "d 2",
+	})
+	for i := 0; i < 2; i++ {
+		ret, err := postJSON(donePath(t, db, id), nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	h := getHistory(t, id)
+	assert.Equal(t, id, h.Task["id"])
+	if assert.Len(t, h.Completions, 2) {
+		assert.Equal(t, now.Format(`20060102`), h.Completions[0]["date"])
+		assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), h.Completions[1]["date"])
+		assert.NotEmpty(t, h.Completions[0]["done_at"])
+	}
+
+	once := addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: "Отправить отчёт",
+	})
+	ret, err := postJSON(donePath(t, db, once), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, once)
+
+	h = getHistory(t, once)
+	assert.Equal(t, "Отправить отчёт", h.Task["title"])
+	assert.NotEmpty(t, h.Task["completed_at"])
+	assert.Len(t, h.Completions, 1)
+
+	assert.NotContains(t, taskIDs(t), once, "Выполненная задача не должна попадать в список")
+
+	body, err := requestJSON("api/task/history?id=99999999", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.NotEmpty(t, m["error"])
+}
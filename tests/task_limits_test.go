@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoneMaxCount(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"date":      now.Format(`20060102`),
+		"title":     "Три тренировки",
+		"repeat":    "d 2",
+		"max_count": 3,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id, ok := ret["id"].(string)
+	assert.True(t, ok)
+
+	for i := 1; i <= 2; i++ {
+		ret, err = postJSON(donePath(t, db, id), nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var task Task
+		err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
+		assert.NoError(t, err)
+		assert.Equal(t, i, task.DoneCount)
+		assert.Equal(t, now.AddDate(0, 0, 2*i).Format(`20060102`), task.Date)
+	}
+
+	ret, err = postJSON(donePath(t, db, id), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
+
+func TestDoneEndDate(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"date":     now.Format(`20060102`),
+		"title":    "До конца недели",
+		"repeat":   "d 3",
+		"end_date": now.AddDate(0, 0, 4).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id, ok := ret["id"].(string)
+	assert.True(t, ok)
+
+	body, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 4).Format(`20060102`), body["end_date"])
+
+	ret, err = postJSON(donePath(t, db, id), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var task Task
+	err = db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), task.Date)
+
+	ret, err = postJSON(donePath(t, db, id), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
+
+func TestRepeatLimitsValidation(t *testing.T) {
+	now := time.Now()
+	tbl := []map[string]any{
+		{"title": "Разовая", "end_date": now.Format(`20060102`)},
+		{"title": "Разовая", "max_count": 2},
+		{"title": "Отрицательная", "repeat": "d 1", "max_count": -1},
+		{"title": "Формат", "repeat": "d 1", "end_date": "01.02.2024"},
+		{"title": "Раньше", "date": now.Format(`20060102`), "repeat": "d 1",
+			"end_date": now.AddDate(0, 0, -1).Format(`20060102`)},
+	}
+	for _, v := range tbl {
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], "Ожидается ошибка для задачи %v", v)
+	}
+}
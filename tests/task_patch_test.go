@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchTask(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// просроченная задача: при правке комментария её дата не должна переехать на сегодня
+	var id string
+	err := db.Get(&id, db.Rebind(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?) RETURNING id`),
+		"20240105", "Сдать показания счётчиков", "", "")
+	assert.NoError(t, err)
+
+	code, etag, ret := requestIfMatch(t, "api/task?id="+id, `"1"`, map[string]any{"comment": "Вода и свет"}, http.MethodPatch)
+	assert.Equal(t, http.StatusOK, code, ret)
+	assert.Equal(t, `"2"`, etag)
+	assert.Equal(t, id, ret["id"])
+	assert.Equal(t, "Сдать показания счётчиков", ret["title"])
+	assert.Equal(t, "Вода и свет", ret["comment"])
+	assert.Equal(t, "20240105", ret["date"])
+	assert.Equal(t, "2", ret["version"])
+
+	code, _, ret = requestIfMatch(t, "api/task?id="+id, "", map[string]any{"comment": nil, "version": "2"}, http.MethodPatch)
+	assert.Equal(t, http.StatusOK, code, ret)
+	assert.Empty(t, ret["comment"], "null очищает поле")
+	assert.Equal(t, "Сдать показания счётчиков", getTask(t, db, id).Title)
+
+	today := time.Now().Format(`20060102`)
+	code, _, ret = requestIfMatch(t, "api/task?id="+id, `"3"`, map[string]any{"repeat": "d 30"}, http.MethodPatch)
+	assert.Equal(t, http.StatusOK, code, ret)
+	assert.Equal(t, "d 30", ret["repeat"])
+	assert.GreaterOrEqual(t, ret["date"], today, "При смене правила повторения дата пересчитывается")
+
+	for _, tc := range []struct {
+		patch map[string]any
+		field string
+	}{
+		{map[string]any{"title": nil}, "title"},
+		{map[string]any{"title": ""}, "title"},
+		{map[string]any{"repeat": "x 1"}, "repeat"},
+		{map[string]any{"date": "05.01.2024"}, "date"},
+		{map[string]any{"repeat": nil, "max_count": 3}, "max_count"},
+	} {
+		code, _, ret = requestIfMatch(t, "api/task?id="+id, `"4"`, tc.patch, http.MethodPatch)
+		assert.Equal(t, http.StatusBadRequest, code, tc.patch)
+		assert.Equal(t, tc.field, ret["field"], tc.patch)
+	}
+	for _, patch := range []map[string]any{
+		{"id": "1"},
+		{"done_count": 5},
+		{"list_id": "1"},
+		{"colour": "red"},
+		{"max_count": "три"},
+	} {
+		code, _, ret = requestIfMatch(t, "api/task?id="+id, `"4"`, patch, http.MethodPatch)
+		assert.Equal(t, http.StatusBadRequest, code, patch)
+		assert.NotEmpty(t, ret["error"], patch)
+	}
+	assert.Equal(t, int64(4), getTask(t, db, id).Version, "Отклонённые изменения не должны менять задачу")
+
+	code, _, _ = requestIfMatch(t, "api/task?id="+id, "", map[string]any{"comment": "Без версии"}, http.MethodPatch)
+	assert.Equal(t, http.StatusPreconditionRequired, code)
+	code, _, ret = requestIfMatch(t, "api/task?id="+id, `"2"`, map[string]any{"comment": "Устаревшая правка"}, http.MethodPatch)
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	if current, ok := ret["task"].(map[string]any); assert.True(t, ok, ret) {
+		assert.Equal(t, "4", current["version"])
+	}
+
+	code, _, _ = requestIfMatch(t, "api/task?id=999999999", "*", map[string]any{"comment": "Нет такой"}, http.MethodPatch)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = requestIfMatch(t, "api/task", "*", map[string]any{"comment": "Без id"}, http.MethodPatch)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
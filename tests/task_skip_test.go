@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTask(t *testing.T, db *sqlx.DB, id string) Task {
+	var task Task
+	err := db.Get(&task, db.Rebind(`SELECT * FROM scheduler WHERE id=?`), id)
+	assert.NoError(t, err)
+	return task
+}
+
+// donePath — адрес /api/task/done для задачи id с её текущей версией
+func donePath(t *testing.T, db *sqlx.DB, id string) string {
+	return "api/task/done?id=" + id + "&version=" + strconv.FormatInt(getTask(t, db, id).Version, 10)
+}
+
+func TestSkipAndMoveTask(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	id := addTask(t, task{
+		date:   day(0),
+		title:  "Бассейн",
+		repeat: "d 7",
+	})
+
+	ret, err := postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	tsk := getTask(t, db, id)
+	assert.Equal(t, day(7), tsk.Date)
+	assert.Equal(t, 0, tsk.DoneCount)
+
+	ret, err = postJSON("api/task/move?id="+id+"&date="+day(9), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	tsk = getTask(t, db, id)
+	assert.Equal(t, day(9), tsk.Date)
+	assert.Equal(t, day(7), tsk.AnchorDate)
+	assert.Equal(t, "d 7", tsk.Repeat)
+
+	// перенести можно не раньше сегодняшнего дня и только до следующего повторения
+	for _, date := range []string{day(-1), day(14), day(20)} {
+		ret, err = postJSON("api/task/move?id="+id+"&date="+date, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, "date", ret["field"], "Перенос на %s должен быть отклонён", date)
+	}
+	assert.Equal(t, day(9), getTask(t, db, id).Date)
+
+	ret, err = postJSON(donePath(t, db, id), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	tsk = getTask(t, db, id)
+	assert.Equal(t, day(14), tsk.Date)
+	assert.Empty(t, tsk.AnchorDate)
+
+	body, err := requestJSON("api/task/exceptions?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	if assert.Len(t, m["exceptions"], 2) {
+		assert.Equal(t, day(0), m["exceptions"][0]["date"])
+		assert.Empty(t, m["exceptions"][0]["moved_to"])
+		assert.Equal(t, day(7), m["exceptions"][1]["date"])
+		assert.Equal(t, day(9), m["exceptions"][1]["moved_to"])
+	}
+
+	ret, err = postJSON("api/task/move?id="+id+"&date=28.01.2024", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// "d 1" на сегодня пропускается на завтра, а не остаётся на сегодня
+	daily := addTask(t, task{date: day(0), title: "Зарядка", repeat: "d 1"})
+	ret, err = postJSON("api/task/skip?id="+daily, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, day(1), getTask(t, db, daily).Date)
+	ret, err = postJSON("api/task/skip?id="+daily, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, day(2), getTask(t, db, daily).Date)
+
+	once := addTask(t, task{date: day(0), title: "Разовая задача"})
+	for _, path := range []string{"api/task/skip?id=" + once, "api/task/move?id=" + once + "&date=" + day(1)} {
+		ret, err = postJSON(path, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "Ожидается ошибка для %s", path)
+	}
+}
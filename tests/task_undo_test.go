@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestUndoToken выполняет запрос и возвращает токен отмены из заголовка ответа
+func requestUndoToken(t *testing.T, apipath string, method string) string {
+	_, header, body := doRequest(t, method, apipath, nil, withCookie(Token))
+	assert.JSONEq(t, `{}`, string(body))
+	return header.Get("X-Undo-Token")
+}
+
+func undo(t *testing.T, token string) map[string]any {
+	body, err := requestJSON("api/undo?token="+token, nil, http.MethodPost)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func TestUndo(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "d 3",
+	})
+
+	token := requestUndoToken(t, donePath(t, db, id), http.MethodPost)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), getTask(t, db, id).Date)
+
+	ret := undo(t, token)
+	assert.Equal(t, id, ret["id"])
+	task := getTask(t, db, id)
+	assert.Equal(t, now.Format(`20060102`), task.Date)
+	assert.Equal(t, 0, task.DoneCount)
+	assert.Empty(t, getHistory(t, id).Completions)
+
+	ret = undo(t, token)
+	assert.NotEmpty(t, ret["error"], "Токен отмены должен действовать один раз")
+
+	_, err := postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	_, err = postJSON(donePath(t, db, id), nil, http.MethodPost)
+	assert.NoError(t, err)
+	before := getTask(t, db, id)
+
+	token = requestUndoToken(t, "api/task?id="+id, http.MethodDelete)
+	assert.NotEmpty(t, token)
+	notFoundTask(t, id)
+
+	ret = undo(t, token)
+	assert.Equal(t, id, ret["id"])
+	// удаление и восстановление — два изменения, после которых у задачи новая версия
+	before.Version += 2
+	assert.Equal(t, before, getTask(t, db, id))
+	assert.Len(t, getHistory(t, id).Completions, 1)
+
+	body, err := requestJSON("api/task/exceptions?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var exceptions struct {
+		Exceptions []map[string]string `json:"exceptions"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &exceptions))
+	assert.Len(t, exceptions.Exceptions, 1)
+
+	// токен можно передать и в том же заголовке, в котором он пришёл
+	token = requestUndoToken(t, "api/task?id="+id, http.MethodDelete)
+	code, _, _ := doRequest(t, http.MethodPost, "api/undo", nil, withCookie(Token), withHeader("X-Undo-Token", token))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, before.Title, getTask(t, db, id).Title)
+	assert.Empty(t, getTask(t, db, id).DeletedAt)
+
+	// изменение после выполнения не даёт его отменить: отмена затёрла бы новую версию задачи
+	token = requestUndoToken(t, donePath(t, db, id), http.MethodPost)
+	changed := getTask(t, db, id)
+	_, err = postJSON("api/task", map[string]any{
+		"id": id, "date": changed.Date, "title": "Полить кактус", "repeat": changed.Repeat,
+		"version": strconv.FormatInt(changed.Version, 10),
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	ret = undo(t, token)
+	assert.NotEmpty(t, ret["error"])
+	if current, ok := ret["task"].(map[string]any); assert.True(t, ok, "Ответ на конфликт должен содержать задачу") {
+		assert.Equal(t, "Полить кактус", current["title"])
+	}
+	assert.Equal(t, changed.Date, getTask(t, db, id).Date)
+
+	ret = undo(t, "unknown")
+	assert.NotEmpty(t, ret["error"])
+}
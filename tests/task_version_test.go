@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestIfMatch выполняет запрос с заголовком If-Match (если он не пуст) и возвращает код ответа,
+// ETag и разобранный JSON
+func requestIfMatch(t *testing.T, apipath string, ifMatch string, values map[string]any, method string) (int, string, map[string]any) {
+	code, header, body := doRequest(t, method, apipath, values, withCookie(Token), withHeader("If-Match", ifMatch))
+	return code, header.Get("ETag"), decodeBody(t, body)
+}
+
+func TestTaskVersion(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	id := addTask(t, task{date: today, title: "Заказать пиццу", repeat: "d 1"})
+
+	code, etag, ret := requestIfMatch(t, "api/task?id="+id, "", nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `"1"`, etag)
+	assert.Equal(t, "1", ret["version"])
+
+	edit := map[string]any{"id": id, "date": today, "title": "Заказать пиццу на всех", "repeat": "d 1"}
+	code, _, ret = requestIfMatch(t, "api/task", "", edit, http.MethodPut)
+	assert.Equal(t, http.StatusPreconditionRequired, code, "Без версии изменение не принимается")
+	assert.NotEmpty(t, ret["error"])
+
+	code, etag, _ = requestIfMatch(t, "api/task", `"1"`, edit, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `"2"`, etag)
+	assert.Equal(t, int64(2), getTask(t, db, id).Version)
+
+	// второй клиент всё ещё видит версию 1 и не должен затереть изменение первого
+	stale := map[string]any{"id": id, "date": today, "title": "Заказать суши", "repeat": "d 1"}
+	for _, tc := range []struct {
+		ifMatch string
+		version string
+	}{{`"1"`, ""}, {"", "1"}, {`W/"2"`, ""}} {
+		if tc.version != "" {
+			stale["version"] = tc.version
+		}
+		code, etag, ret = requestIfMatch(t, "api/task", tc.ifMatch, stale, http.MethodPut)
+		assert.Equal(t, http.StatusPreconditionFailed, code, tc)
+		assert.Equal(t, `"2"`, etag)
+		if current, ok := ret["task"].(map[string]any); assert.True(t, ok, ret) {
+			assert.Equal(t, "Заказать пиццу на всех", current["title"])
+			assert.Equal(t, "2", current["version"])
+		}
+		delete(stale, "version")
+	}
+	assert.Equal(t, "Заказать пиццу на всех", getTask(t, db, id).Title)
+
+	code, _, _ = requestIfMatch(t, "api/task/done?id="+id, "", nil, http.MethodPost)
+	assert.Equal(t, http.StatusPreconditionRequired, code)
+	code, _, _ = requestIfMatch(t, "api/task/done?id="+id+"&version=1", "", nil, http.MethodPost)
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	code, _, _ = requestIfMatch(t, "api/task/done?id="+id+"&version=abc", "", nil, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, today, getTask(t, db, id).Date, "Отклонённое выполнение не должно менять задачу")
+
+	code, etag, _ = requestIfMatch(t, "api/task/done?id="+id, `"2"`, nil, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `"3"`, etag)
+	code, _, _ = requestIfMatch(t, "api/task", "*", stale, http.MethodPut)
+	assert.Equal(t, http.StatusOK, code, "If-Match: * подходит к любой версии")
+
+	code, _, _ = requestIfMatch(t, "api/task", `"1"`, map[string]any{"id": "999999999", "date": today, "title": "Нет такой"}, http.MethodPut)
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksFilters(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+	// задачи с прошедшей датой можно добавить только напрямую: API переносит их на сегодня
+	_, err = db.Exec(db.Rebind(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, '', ''), (?, ?, '', 'd 7')`),
+		day(-3), "Просроченный отчёт", day(-1), "Просроченная тренировка")
+	assert.NoError(t, err)
+	addTask(t, task{date: day(0), title: "Сегодняшняя задача"})
+	addTask(t, task{date: day(2), title: "Полить цветы", repeat: "d 3"})
+	addTask(t, task{date: day(6), title: "Купить подарок"})
+	addTask(t, task{date: day(10), title: "Сдать отчёт"})
+
+	check := func(params url.Values, titles ...string) {
+		page := getTasksPage(t, params)
+		assert.Empty(t, page.Error, params.Encode())
+		assert.Equal(t, titles, pageTitles(page), params.Encode())
+	}
+
+	check(url.Values{"from": {day(0)}, "to": {day(6)}},
+		"Сегодняшняя задача", "Полить цветы", "Купить подарок")
+	check(url.Values{"from": {day(6)}}, "Купить подарок", "Сдать отчёт")
+	check(url.Values{"overdue": {"true"}}, "Просроченный отчёт", "Просроченная тренировка")
+	check(url.Values{"overdue": {"true"}, "repeating": {"false"}}, "Просроченный отчёт")
+	check(url.Values{"repeating": {"true"}}, "Просроченная тренировка", "Полить цветы")
+	if Search {
+		check(url.Values{"search": {"отчёт"}, "overdue": {"true"}}, "Просроченный отчёт")
+	}
+
+	page := getTasksPage(t, url.Values{"to": {day(6)}, "limit": {"2"}})
+	assert.NotEmpty(t, page.NextCursor)
+	page = getTasksPage(t, url.Values{"to": {day(6)}, "limit": {"2"}, "cursor": {page.NextCursor}})
+	assert.Equal(t, []string{"Сегодняшняя задача", "Полить цветы"}, pageTitles(page))
+	assert.NotEmpty(t, getTasksPage(t, url.Values{"to": {day(1)}, "limit": {"2"}, "cursor": {page.PrevCursor}}).Error)
+
+	for _, params := range []url.Values{
+		{"from": {"01.02.2024"}},
+		{"from": {day(5)}, "to": {day(1)}},
+		{"overdue": {"yes"}},
+		{"repeating": {"maybe"}},
+	} {
+		assert.NotEmpty(t, getTasksPage(t, params).Error, params.Encode())
+	}
+}
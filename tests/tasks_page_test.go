@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tasksPage struct {
+	Tasks      []map[string]string `json:"tasks"`
+	NextCursor string              `json:"next_cursor"`
+	PrevCursor string              `json:"prev_cursor"`
+	Error      string              `json:"error"`
+}
+
+func getTasksPage(t *testing.T, params url.Values) tasksPage {
+	body, err := requestJSON("api/tasks?"+params.Encode(), nil, http.MethodGet)
+	assert.NoError(t, err)
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	return page
+}
+
+func pageTitles(page tasksPage) []string {
+	titles := make([]string, len(page.Tasks))
+	for i, v := range page.Tasks {
+		titles[i] = v["title"]
+	}
+	return titles
+}
+
+func TestTasksPagination(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	// две задачи на одну дату, чтобы проверить порядок при одинаковом ключе
+	for i, days := range []int{3, 0, 5, 1, 1, 6, 2} {
+		addTask(t, task{
+			date:  now.AddDate(0, 0, days).Format(`20060102`),
+			title: fmt.Sprintf("Задача %d", i+1),
+		})
+	}
+
+	params := url.Values{"limit": {"3"}}
+	page := getTasksPage(t, params)
+	assert.Equal(t, []string{"Задача 2", "Задача 4", "Задача 5"}, pageTitles(page))
+	assert.Empty(t, page.PrevCursor)
+	assert.NotEmpty(t, page.NextCursor)
+
+	params.Set("cursor", page.NextCursor)
+	page = getTasksPage(t, params)
+	assert.Equal(t, []string{"Задача 7", "Задача 1", "Задача 3"}, pageTitles(page))
+	assert.NotEmpty(t, page.PrevCursor)
+
+	params.Set("cursor", page.NextCursor)
+	last := getTasksPage(t, params)
+	assert.Equal(t, []string{"Задача 6"}, pageTitles(last))
+	assert.Empty(t, last.NextCursor)
+
+	params.Set("cursor", last.PrevCursor)
+	page = getTasksPage(t, params)
+	assert.Equal(t, []string{"Задача 7", "Задача 1", "Задача 3"}, pageTitles(page))
+	params.Set("cursor", page.PrevCursor)
+	page = getTasksPage(t, params)
+	assert.Equal(t, []string{"Задача 2", "Задача 4", "Задача 5"}, pageTitles(page))
+	assert.Empty(t, page.PrevCursor)
+
+	page = getTasksPage(t, url.Values{"limit": {"4"}, "sort": {"title"}, "order": {"desc"}})
+	assert.Equal(t, []string{"Задача 7", "Задача 6", "Задача 5", "Задача 4"}, pageTitles(page))
+	next := getTasksPage(t, url.Values{"limit": {"4"}, "sort": {"title"}, "order": {"desc"}, "cursor": {page.NextCursor}})
+	assert.Equal(t, []string{"Задача 3", "Задача 2", "Задача 1"}, pageTitles(next))
+
+	page = getTasksPage(t, url.Values{"sort": {"created"}})
+	assert.Equal(t, "Задача 1", page.Tasks[0]["title"])
+	assert.NotEmpty(t, page.Tasks[0]["created_at"])
+
+	if Search {
+		page = getTasksPage(t, url.Values{"search": {"задача"}, "limit": {"5"}})
+		assert.Len(t, page.Tasks, 5)
+		next = getTasksPage(t, url.Values{"search": {"задача"}, "limit": {"5"}, "cursor": {page.NextCursor}})
+		assert.Len(t, next.Tasks, 2)
+		assert.Empty(t, next.NextCursor)
+		assert.NotEmpty(t, next.PrevCursor)
+	}
+
+	for _, params := range []url.Values{
+		{"sort": {"priority"}},
+		{"order": {"up"}},
+		{"limit": {"0"}},
+		{"cursor": {"broken"}},
+		{"cursor": {last.PrevCursor}, "sort": {"id"}},
+	} {
+		assert.NotEmpty(t, getTasksPage(t, params).Error, params.Encode())
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksSearchIgnoreCase(t *testing.T) {
+	if !Search {
+		return
+	}
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	addTask(t, task{
+		date:    now.Format(`20060102`),
+		title:   "Купить Молоко",
+		comment: "",
+	})
+	addTask(t, task{
+		date:    now.AddDate(0, 0, 1).Format(`20060102`),
+		title:   "Позвонить маме",
+		comment: "Спросить про МОЛОКО",
+	})
+	addTask(t, task{
+		date:  now.AddDate(0, 0, 2).Format(`20060102`),
+		title: "Сходить в бассейн",
+	})
+
+	tasks := getTasks(t, "молоко")
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "Купить Молоко", tasks[0]["title"])
+		assert.Equal(t, "Позвонить маме", tasks[1]["title"])
+	}
+	assert.Empty(t, getTasks(t, "кефир"))
+	assert.Len(t, getTasks(t, now.AddDate(0, 0, 2).Format(`02.01.2006`)), 1)
+}
+
+func TestTasksSearchPhrase(t *testing.T) {
+	if !Search {
+		return
+	}
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	addTask(t, task{
+		date:    now.Format(`20060102`),
+		title:   "Купить свежее молоко",
+		comment: "в магазине у дома",
+	})
+	addTask(t, task{
+		date:    now.AddDate(0, 0, 1).Format(`20060102`),
+		title:   "Позвонить",
+		comment: "про молоко и свежий хлеб",
+	})
+
+	tasks := getTasks(t, url.QueryEscape("молок"))
+	assert.Len(t, tasks, 2)
+	tasks = getTasks(t, url.QueryEscape(`"свежее молоко"`))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "Купить свежее молоко", tasks[0]["title"])
+	}
+	assert.Len(t, getTasks(t, url.QueryEscape("хлеб молоко")), 1)
+}
+
+// TestTasksFullTextSearch проверяет поиск по индексу FTS4, который в SQLite есть в любой сборке
+func TestTasksFullTextSearch(t *testing.T) {
+	if !Search {
+		return
+	}
+	db := openDB(t)
+	defer db.Close()
+	if db.DriverName() != "sqlite3" {
+		t.Skip("Полнотекстовый индекс есть только в SQLite")
+	}
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	addTask(t, task{
+		date:    now.Format(`20060102`),
+		title:   "Зайти в магазин",
+		comment: "купить молоко",
+	})
+	cat := addTask(t, task{
+		date:  now.AddDate(0, 0, 1).Format(`20060102`),
+		title: "Молоко для кота",
+	})
+
+	tasks := getTasks(t, url.QueryEscape("молок"))
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "Молоко для кота", tasks[0]["title"], "Совпадение в заголовке важнее совпадения в комментарии")
+		assert.Equal(t, "<mark>Молоко</mark> для кота", tasks[0]["snippet"])
+		assert.Equal(t, "купить <mark>молоко</mark>", tasks[1]["snippet"])
+	}
+	page := getTasksPage(t, url.Values{"search": {"молок"}, "limit": {"1"}})
+	next := getTasksPage(t, url.Values{"search": {"молок"}, "limit": {"1"}, "cursor": {page.NextCursor}})
+	assert.Equal(t, []string{"Молоко для кота"}, pageTitles(page))
+	assert.Equal(t, []string{"Зайти в магазин"}, pageTitles(next), "Страницы идут в порядке релевантности")
+
+	putWithRequestID(t, "search-"+cat, map[string]any{
+		"id": cat, "date": now.AddDate(0, 0, 1).Format(`20060102`), "title": "Корм для кота", "version": "1",
+	})
+	tasks = getTasks(t, url.QueryEscape("молок"))
+	if assert.Len(t, tasks, 1, "Индекс должен обновиться вместе с задачей") {
+		assert.Equal(t, "Зайти в магазин", tasks[0]["title"])
+	}
+	tasks = getTasks(t, url.QueryEscape("корм"))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "<mark>Корм</mark> для кота", tasks[0]["snippet"])
+	}
+
+	addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: `<img src=x onerror="alert(1)"> вода & хлеб`,
+	})
+	tasks = getTasks(t, url.QueryEscape("вода"))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>вода</mark> &amp; хлеб`, tasks[0]["snippet"],
+			"Текст задачи во фрагменте должен быть экранирован для HTML")
+	}
+
+	var indexed int
+	assert.NoError(t, db.Get(&indexed, "SELECT COUNT(*) FROM scheduler_search WHERE scheduler_search MATCH 'молок*'"))
+	assert.Equal(t, 1, indexed)
+}
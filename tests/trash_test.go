@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTrash(t *testing.T) []map[string]string {
+	body, err := requestJSON("api/trash", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var trash struct {
+		Tasks []map[string]string `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &trash))
+	return trash.Tasks
+}
+
+func inTrash(t *testing.T, id string) bool {
+	for _, task := range getTrash(t) {
+		if task["id"] == id {
+			assert.NotEmpty(t, task["deleted_at"])
+			return true
+		}
+	}
+	return false
+}
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		title:  "Разобрать кладовку",
+		repeat: "d 5",
+	})
+	_, err := postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.False(t, inTrash(t, id))
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+	assert.True(t, inTrash(t, id))
+	assert.NotEmpty(t, getTask(t, db, id).DeletedAt, "Удалённая задача должна остаться в базе")
+	assert.NotContains(t, taskIDs(t), id)
+
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.False(t, inTrash(t, id))
+	restored := getTask(t, db, id)
+	assert.Empty(t, restored.DeletedAt)
+	assert.Equal(t, "Разобрать кладовку", restored.Title)
+
+	body, err := requestJSON("api/task/exceptions?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var exceptions struct {
+		Exceptions []map[string]string `json:"exceptions"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &exceptions))
+	assert.Len(t, exceptions.Exceptions, 1, "Переносы должны сохраниться после восстановления")
+
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Задачу не из корзины восстановить нельзя")
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"], "Задачу не из корзины нельзя удалить окончательно")
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.False(t, inTrash(t, id))
+	var count int
+	err = db.Get(&count, db.Rebind(`SELECT count(*) FROM scheduler WHERE id = ?`), id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	ret, err = postJSON("api/trash", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
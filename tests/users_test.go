@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestOption дополняет запрос, который выполняет doRequest: ставит cookie или заголовок
+type requestOption func(req *http.Request)
+
+// withCookie передаёт токен входа в cookie; пустой токен не передаётся
+func withCookie(token string) requestOption {
+	return func(req *http.Request) {
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "token", Value: token})
+		}
+	}
+}
+
+// withHeader задаёт заголовок запроса; пустое значение не передаётся
+func withHeader(name string, value string) requestOption {
+	return func(req *http.Request) {
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+}
+
+// doRequest отправляет values в JSON и возвращает код ответа, заголовки и тело
+func doRequest(t *testing.T, method string, apipath string, values map[string]any, options ...requestOption) (int, http.Header, []byte) {
+	var data []byte
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for _, option := range options {
+		option(req)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, nil, nil
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, resp.Header, body
+}
+
+// decodeBody разбирает JSON из тела ответа; пустое тело — nil
+func decodeBody(t *testing.T, body []byte) map[string]any {
+	var m map[string]any
+	if len(body) > 0 {
+		assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	}
+	return m
+}
+
+// requestAs выполняет запрос с токеном пользователя и возвращает код ответа и разобранный JSON
+func requestAs(t *testing.T, token string, apipath string, values map[string]any, method string) (int, map[string]any) {
+	code, _, body := doRequest(t, method, apipath, values, withCookie(token))
+	return code, decodeBody(t, body)
+}
+
+func registerUser(t *testing.T, login string) string {
+	credentials := map[string]any{"login": login, "password": "correct horse"}
+	code, ret := requestAs(t, "", "api/register", credentials, http.MethodPost)
+	assert.Equal(t, http.StatusCreated, code, ret)
+	assert.NotEmpty(t, ret["id"])
+
+	code, ret = requestAs(t, "", "api/login", credentials, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code, ret)
+	token, _ := ret["token"].(string)
+	assert.NotEmpty(t, token)
+	return token
+}
+
+func TestUsers(t *testing.T) {
+	suffix := time.Now().Format("150405.000000")
+	alice := registerUser(t, "alice-"+suffix)
+	bob := registerUser(t, "bob-"+suffix)
+
+	code, ret := requestAs(t, "", "api/register", map[string]any{"login": "alice-" + suffix, "password": "another one"}, http.MethodPost)
+	assert.Equal(t, http.StatusConflict, code)
+	code, ret = requestAs(t, "", "api/register", map[string]any{"login": "x", "password": "short"}, http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "login", ret["field"])
+	code, _ = requestAs(t, "", "api/login", map[string]any{"login": "alice-" + suffix, "password": "wrong password"}, http.MethodPost)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = requestAs(t, "broken", "api/tasks", nil, http.MethodGet)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, ret = requestAs(t, alice, "api/task", map[string]any{
+		"date":  time.Now().Format(`20060102`),
+		"title": "Задача Алисы",
+	}, http.MethodPost)
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(ret["id"])
+
+	code, ret = requestAs(t, alice, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Задача Алисы", ret["title"])
+
+	code, _ = requestAs(t, bob, "api/task?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = requestAs(t, bob, "api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Чужая задача",
+	}, http.MethodPut)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = requestAs(t, bob, "api/task/done?id="+id, nil, http.MethodPost)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = requestAs(t, bob, "api/task?id="+id, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = requestAs(t, bob, "api/task/history?id="+id, nil, http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	_, ret = requestAs(t, bob, "api/tasks", nil, http.MethodGet)
+	assert.Empty(t, ret["tasks"])
+	_, ret = requestAs(t, alice, "api/tasks", nil, http.MethodGet)
+	assert.Len(t, ret["tasks"], 1)
+	assert.NotContains(t, taskIDs(t), id, "Задача пользователя не должна попадать в общий список")
+
+	code, _ = requestAs(t, alice, "api/task?id="+id, nil, http.MethodDelete)
+	assert.Equal(t, http.StatusOK, code)
+}